@@ -5,6 +5,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/storage"
+	"github.com/vvvigya/latex-editor/api-service/internal/tracing"
 )
 
 func TestHealth(t *testing.T) {
@@ -21,14 +28,44 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealthReportsStorageReachability(t *testing.T) {
+	prevStorage := fileStorage
+	fileStorage = storage.NewMemStorage()
+	defer func() { fileStorage = prevStorage }()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	rec := httptest.NewRecorder()
+	handleHealth(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	var body HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if body.Status != "ok" || body.Storage != "ok" {
+		t.Fatalf("unexpected health body: %#v", body)
+	}
+}
+
 func TestVersion(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(prev)
+
 	req := httptest.NewRequest(http.MethodGet, "/api/version", nil)
 	rec := httptest.NewRecorder()
-	handleVersion(rec, req)
+	traced := tracing.Middleware(func(r *http.Request) string { return r.URL.Path })(http.HandlerFunc(handleVersion))
+	traced.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("unexpected status: %d", rec.Code)
 	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected Content-Type application/json (via httpx.Respond), got %q", ct)
+	}
 
 	var body map[string]string
 	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
@@ -40,4 +77,9 @@ func TestVersion(t *testing.T) {
 	if _, ok := body["uptime"]; !ok {
 		t.Fatalf("missing uptime field")
 	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "/api/version" {
+		t.Fatalf("expected one span named /api/version, got %#v", spans)
+	}
 }
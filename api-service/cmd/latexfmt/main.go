@@ -0,0 +1,59 @@
+// Command latexfmt formats .tex files the way gofmt does for Go source:
+// it prints the result, writes it back with -w, or lists files whose
+// formatting differs with -l.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/format"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write result to (rather than print) the source file")
+	list := flag.Bool("l", false, "list files whose formatting differs from latexfmt's")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: latexfmt [-w] [-l] file.tex [file.tex ...]")
+		os.Exit(2)
+	}
+
+	exit := 0
+	for _, path := range flag.Args() {
+		if err := formatFile(path, *write, *list); err != nil {
+			fmt.Fprintf(os.Stderr, "latexfmt: %s: %v\n", path, err)
+			exit = 1
+		}
+	}
+	os.Exit(exit)
+}
+
+func formatFile(path string, write, list bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	edits, err := format.Format(context.Background(), src, format.Range{})
+	if err != nil {
+		return err
+	}
+	if len(edits) == 0 && (write || list) {
+		return nil
+	}
+	if list {
+		fmt.Println(path)
+		return nil
+	}
+
+	out := format.Apply(src, edits)
+	if write {
+		return os.WriteFile(path, out, 0o644)
+	}
+	os.Stdout.Write(out)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/comments"
+)
+
+func TestCommentsPostListReplyAndResolve(t *testing.T) {
+	commentsStore = comments.NewMemStore()
+	documentID := "proj1:main.tex"
+
+	postBody, _ := json.Marshal(CommentRequest{
+		Author:  "ada",
+		Message: "this derivation looks off",
+		Anchor:  comments.Anchor{Line: 3, Col: 0, Len: 10, LineText: "x = y + z"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/documents/"+documentID+"/comments", bytes.NewReader(postBody))
+	rec := httptest.NewRecorder()
+	handlePostComment(rec, req, documentID)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("post comment: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var created comments.Comment
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decoding created comment: %v", err)
+	}
+	if created.ID == "" || created.Message != "this derivation looks off" {
+		t.Fatalf("unexpected created comment: %#v", created)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/documents/"+documentID+"/comments", nil)
+	rec = httptest.NewRecorder()
+	handleListComments(rec, req, documentID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list comments: unexpected status %d", rec.Code)
+	}
+	var listed CommentListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("decoding comment list: %v", err)
+	}
+	if len(listed.Comments) != 1 || listed.Comments[0].ID != created.ID {
+		t.Fatalf("unexpected comment list: %#v", listed.Comments)
+	}
+
+	replyBody, _ := json.Marshal(CommentRequest{Author: "bob", Message: "agreed, fixing now"})
+	req = httptest.NewRequest(http.MethodPost, "/api/comments/"+created.ID+"/replies", bytes.NewReader(replyBody))
+	rec = httptest.NewRecorder()
+	handlePostReply(rec, req, created)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("post reply: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var reply comments.Comment
+	if err := json.NewDecoder(rec.Body).Decode(&reply); err != nil {
+		t.Fatalf("decoding reply: %v", err)
+	}
+	if reply.ParentID != created.ID {
+		t.Fatalf("expected reply.ParentID %q, got %q", created.ID, reply.ParentID)
+	}
+
+	resolveBody, _ := json.Marshal(ResolveCommentRequest{Resolved: true})
+	req = httptest.NewRequest(http.MethodPatch, "/api/comments/"+created.ID, bytes.NewReader(resolveBody))
+	rec = httptest.NewRecorder()
+	handleResolveComment(rec, req, created)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("resolve comment: unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resolved comments.Comment
+	if err := json.NewDecoder(rec.Body).Decode(&resolved); err != nil {
+		t.Fatalf("decoding resolved comment: %v", err)
+	}
+	if !resolved.Resolved {
+		t.Fatal("expected comment to be resolved")
+	}
+}
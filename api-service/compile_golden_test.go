@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/goldentest"
+)
+
+var (
+	pdfCreationDateRE = regexp.MustCompile(`/CreationDate\(D:[^)]*\)`)
+	pdfModDateRE      = regexp.MustCompile(`/ModDate\(D:[^)]*\)`)
+	pdfIDRE           = regexp.MustCompile(`/ID\[<[0-9a-f]*><[0-9a-f]*>\]`)
+)
+
+// normalizePDF zeroes the byte ranges of a generated PDF that legitimately
+// vary run-to-run, so two compiles of the same source diff as identical:
+// /CreationDate, /ModDate, and the /ID pair derived from them.
+func normalizePDF(b []byte) []byte {
+	s := string(b)
+	s = pdfCreationDateRE.ReplaceAllString(s, "/CreationDate(D:REDACTED)")
+	s = pdfModDateRE.ReplaceAllString(s, "/ModDate(D:REDACTED)")
+	s = pdfIDRE.ReplaceAllString(s, "/ID[<REDACTED><REDACTED>]")
+	return []byte(s)
+}
+
+// TestCompileOutputMatchesGolden guards writePlaceholderPDF's output
+// shape against accidental regressions; run with -update after a
+// deliberate change to the stub renderer to refresh the fixtures.
+func TestCompileOutputMatchesGolden(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{name: "empty", content: ""},
+		{name: "hello", content: "Hello, LaTeX Preview!"},
+		{name: "multiline", content: "Line one\\\\Line two"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dst := filepath.Join(dir, "output.pdf")
+			if err := writePlaceholderPDF(dst, tc.content); err != nil {
+				t.Fatalf("writePlaceholderPDF: %v", err)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatalf("reading generated pdf: %v", err)
+			}
+			goldenPath := filepath.Join("testdata", "golden", tc.name+".pdf.golden")
+			goldentest.Bytes(t, goldenPath, got, normalizePDF)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Package auth provides JWT-based authentication, per-project role
+// membership, and scoped share-link tokens for the API and its
+// WebSocket endpoints.
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Role is a project membership level. Roles are ordered: owner > editor
+// > viewer, and AtLeast checks use that ordering.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// AtLeast reports whether r grants at least the privilege of min.
+func (r Role) AtLeast(min Role) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
+// Claims is the JWT payload for both logged-in users and share links.
+// Share-link tokens set ProjectID/Role and leave UserID empty.
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID    string `json:"uid,omitempty"`
+	Email     string `json:"email,omitempty"`
+	ProjectID string `json:"pid,omitempty"` // set only on share-link tokens
+	Role      Role   `json:"role,omitempty"`
+}
+
+var ErrInvalidToken = errors.New("auth: invalid or expired token")
+
+// Signer issues and verifies HS256 JWTs off a single server secret.
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret string) *Signer {
+	return &Signer{secret: []byte(secret)}
+}
+
+// IssueUserToken mints a token identifying a logged-in user, valid for ttl.
+func (s *Signer) IssueUserToken(userID, email string, ttl time.Duration) (string, error) {
+	claims := Claims{
+		UserID: userID,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+// IssueShareToken mints a token scoped to a single project and role, with
+// no associated user account. A ttl of 0 means the token never expires.
+func (s *Signer) IssueShareToken(projectID string, role Role, ttl time.Duration) (string, error) {
+	claims := Claims{
+		ProjectID: projectID,
+		Role:      role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if ttl > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+func (s *Signer) Parse(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("auth: unexpected signing method %v", t.Header["alg"])
+		}
+		return s.secret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// HashPassword derives a deterministic, constant-time-comparable digest.
+// A real deployment would use bcrypt/argon2; sha256 keeps this dependency
+// free while the store is still in-memory.
+func HashPassword(password, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + password))
+	return fmt.Sprintf("%x", sum)
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Store holds users and per-project membership in memory. It is a
+// starting point for a DB-backed implementation with the same interface.
+type Store struct {
+	mu      sync.RWMutex
+	users   map[string]*User           // userID -> user
+	byEmail map[string]string          // email -> userID
+	members map[string]map[string]Role // projectID -> userID -> role
+	salt    string
+}
+
+type User struct {
+	ID           string `json:"id"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:   map[string]*User{},
+		byEmail: map[string]string{},
+		members: map[string]map[string]Role{},
+		salt:    "latex-editor", // fixed salt; swap for a per-install secret in a DB-backed store
+	}
+}
+
+var ErrEmailTaken = errors.New("auth: email already registered")
+var ErrBadCredentials = errors.New("auth: invalid email or password")
+
+func (s *Store) Register(id, email, password string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byEmail[email]; ok {
+		return nil, ErrEmailTaken
+	}
+	u := &User{ID: id, Email: email, PasswordHash: HashPassword(password, s.salt)}
+	s.users[id] = u
+	s.byEmail[email] = id
+	return u, nil
+}
+
+func (s *Store) Authenticate(email, password string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.byEmail[email]
+	if !ok {
+		return nil, ErrBadCredentials
+	}
+	u := s.users[id]
+	if !constantTimeEqual(u.PasswordHash, HashPassword(password, s.salt)) {
+		return nil, ErrBadCredentials
+	}
+	return u, nil
+}
+
+// AddMember grants userID a role on projectID, creating the project's
+// membership map on first use. The project's creator is typically added
+// as RoleOwner at project-creation time.
+func (s *Store) AddMember(projectID, userID string, role Role) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.members[projectID] == nil {
+		s.members[projectID] = map[string]Role{}
+	}
+	s.members[projectID][userID] = role
+}
+
+// HasMembers reports whether projectID has any recorded membership at all.
+func (s *Store) HasMembers(projectID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.members[projectID]) > 0
+}
+
+// MemberRole reports userID's role on projectID, if any.
+func (s *Store) MemberRole(projectID, userID string) (Role, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	role, ok := s.members[projectID][userID]
+	return role, ok
+}
+
+func (s *Store) RemoveProject(projectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members, projectID)
+}
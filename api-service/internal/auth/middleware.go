@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type ctxKey int
+
+const principalKey ctxKey = 0
+
+// Principal is whoever a validated token identifies: either a logged-in
+// user (UserID set) or a share-link bearer scoped to one project and role
+// (ProjectID/Role set, UserID empty).
+type Principal struct {
+	UserID    string
+	Email     string
+	ProjectID string // non-empty only for share-link tokens
+	Role      Role
+}
+
+// Middleware extracts a bearer token from the Authorization header or a
+// `token` query parameter (the latter so the WebSocket handshake, which
+// can't set headers from a browser, can still authenticate), validates
+// it, and stores the resulting Principal on the request context. Requests
+// with no or invalid token proceed unauthenticated; handlers that require
+// auth check FromContext themselves.
+func Middleware(signer *Signer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tok := bearerToken(r)
+			if tok != "" {
+				if claims, err := signer.Parse(tok); err == nil {
+					p := Principal{UserID: claims.UserID, Email: claims.Email, ProjectID: claims.ProjectID, Role: claims.Role}
+					r = r.WithContext(context.WithValue(r.Context(), principalKey, p))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// FromContext returns the request's Principal, if Middleware validated one.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
+
+// Allow reports whether the request's principal may act on projectID at
+// role min: a share-link token scoped to that exact project, or a logged
+// in user with sufficient store-backed membership.
+func Allow(ctx context.Context, store *Store, projectID string, min Role) bool {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return false
+	}
+	if p.ProjectID != "" {
+		return p.ProjectID == projectID && p.Role.AtLeast(min)
+	}
+	role, ok := store.MemberRole(projectID, p.UserID)
+	return ok && role.AtLeast(min)
+}
+
+// AllowOrigin builds a websocket.Upgrader-compatible CheckOrigin func from
+// a comma-separated allow-list (e.g. ALLOWED_ORIGINS=https://app.example.com).
+// An empty list allows same-origin requests (no Origin header) only.
+func AllowOrigin(allowList []string) func(r *http.Request) bool {
+	set := map[string]bool{}
+	for _, o := range allowList {
+		o = strings.TrimSpace(o)
+		if o != "" {
+			set[o] = true
+		}
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		return set[origin]
+	}
+}
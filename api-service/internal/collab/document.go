@@ -0,0 +1,156 @@
+package collab
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Committed is one Op sequence that has already been applied to a
+// Document, recorded so later changes can be transformed against it.
+type Committed struct {
+	Revision int  `json:"revision"`
+	Ops      []Op `json:"ops"`
+}
+
+// Document is the server's authoritative state for a single
+// (projectID, entryFile) pair: a monotonically increasing revision and
+// the current text, plus enough history to transform late-arriving
+// changes. History is persisted to an append-only ops.log under the
+// project directory so it survives a restart.
+type Document struct {
+	mu       sync.Mutex
+	Content  string
+	Revision int
+	history  []Committed // ops committed at revision > 0, oldest first
+	logPath  string
+}
+
+// Load opens (or creates) a document backed by ops.log under root, seeded
+// with initialContent if no log exists yet.
+func Load(root, initialContent string) (*Document, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	d := &Document{Content: initialContent, logPath: filepath.Join(root, "ops.log")}
+
+	f, err := os.Open(d.logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+	for scanner.Scan() {
+		var c Committed
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue
+		}
+		content, err := Apply(d.Content, c.Ops)
+		if err != nil {
+			return nil, fmt.Errorf("collab: replaying ops.log: %w", err)
+		}
+		d.Content = content
+		d.Revision = c.Revision
+		d.history = append(d.history, c)
+	}
+	return d, scanner.Err()
+}
+
+// Submit transforms change against every committed op newer than
+// change.BaseRevision, applies the result, assigns it the next revision,
+// appends it to ops.log, and returns the transformed ops plus the new
+// revision so the caller can broadcast them.
+func (d *Document) Submit(change Change) (transformed []Op, revision int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ops := change.Ops
+	for _, c := range d.history {
+		if c.Revision <= change.BaseRevision {
+			continue
+		}
+		ops, _, err = Transform(ops, c.Ops)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	content, err := Apply(d.Content, ops)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	d.Revision++
+	committed := Committed{Revision: d.Revision, Ops: ops}
+	if err := d.appendLog(committed); err != nil {
+		d.Revision--
+		return nil, 0, err
+	}
+	d.Content = content
+	d.history = append(d.history, committed)
+	return ops, d.Revision, nil
+}
+
+// Snapshot returns the current content and revision for bootstrapping a
+// newly subscribed client.
+func (d *Document) Snapshot() (string, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.Content, d.Revision
+}
+
+func (d *Document) appendLog(c Committed) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// Registry keeps one Document per (projectID, entryFile), loading it
+// lazily on first access.
+type Registry struct {
+	mu        sync.Mutex
+	docs      map[string]*Document
+	root      func(projectID, entryFile string) string
+	seedOnNew func(projectID, entryFile string) string
+}
+
+func NewRegistry(root func(projectID, entryFile string) string, seedOnNew func(projectID, entryFile string) string) *Registry {
+	return &Registry{docs: map[string]*Document{}, root: root, seedOnNew: seedOnNew}
+}
+
+func docKey(projectID, entryFile string) string { return projectID + "\x00" + entryFile }
+
+func (r *Registry) Get(projectID, entryFile string) (*Document, error) {
+	key := docKey(projectID, entryFile)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if d, ok := r.docs[key]; ok {
+		return d, nil
+	}
+	seed := ""
+	if r.seedOnNew != nil {
+		seed = r.seedOnNew(projectID, entryFile)
+	}
+	d, err := Load(r.root(projectID, entryFile), seed)
+	if err != nil {
+		return nil, err
+	}
+	r.docs[key] = d
+	return d, nil
+}
@@ -0,0 +1,64 @@
+package collab
+
+import "sync"
+
+// Subscriber is anything that can receive a broadcast payload; the
+// websocket.Conn in api-service satisfies this directly via WriteJSON.
+type Subscriber interface {
+	WriteJSON(v any) error
+}
+
+// Hub multiplexes subscribe/unsubscribe and broadcast for every open
+// document, keyed the same way as Registry.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[string]map[Subscriber]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[string]map[Subscriber]bool{}}
+}
+
+func (h *Hub) Subscribe(projectID, entryFile string, s Subscriber) {
+	key := docKey(projectID, entryFile)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[key] == nil {
+		h.subs[key] = map[Subscriber]bool{}
+	}
+	h.subs[key][s] = true
+}
+
+func (h *Hub) Unsubscribe(projectID, entryFile string, s Subscriber) {
+	key := docKey(projectID, entryFile)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[key], s)
+}
+
+// UnsubscribeAll removes s from every document it was subscribed to, for
+// use when a websocket connection closes.
+func (h *Hub) UnsubscribeAll(s Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, set := range h.subs {
+		delete(set, s)
+	}
+}
+
+// Broadcast sends payload to every subscriber of (projectID, entryFile)
+// except excluding (typically the sender, which gets an ack instead).
+func (h *Hub) Broadcast(projectID, entryFile string, excluding Subscriber, payload any) {
+	key := docKey(projectID, entryFile)
+	h.mu.RLock()
+	targets := make([]Subscriber, 0, len(h.subs[key]))
+	for s := range h.subs[key] {
+		if s != excluding {
+			targets = append(targets, s)
+		}
+	}
+	h.mu.RUnlock()
+	for _, s := range targets {
+		s.WriteJSON(payload)
+	}
+}
@@ -0,0 +1,194 @@
+// Package collab implements operational-transform based collaborative
+// editing for a single LaTeX document. Clients send an Op sequence
+// relative to the revision they last saw (BaseRevision); the server
+// transforms it against every op committed since then, applies it, and
+// broadcasts the transformed op to every other subscriber.
+package collab
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OpKind is one step of an Op sequence.
+type OpKind string
+
+const (
+	OpRetain OpKind = "retain"
+	OpInsert OpKind = "insert"
+	OpDelete OpKind = "delete"
+)
+
+// Op is a single retain/insert/delete step. Retain and Delete carry a
+// character count in N; Insert carries the text to splice in.
+type Op struct {
+	Kind OpKind `json:"kind"`
+	N    int    `json:"n,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// Change is a client's proposed edit: an Op sequence that applies cleanly
+// to the document as of BaseRevision.
+type Change struct {
+	BaseRevision int  `json:"baseRevision"`
+	Ops          []Op `json:"ops"`
+}
+
+func opLen(op Op) int {
+	if op.Kind == OpInsert {
+		return len(op.Text)
+	}
+	return op.N
+}
+
+// spanLength returns how many characters of the *original* document an Op
+// sequence is expected to cover (retains + deletes; inserts are zero-width
+// in the source).
+func spanLength(ops []Op) int {
+	n := 0
+	for _, op := range ops {
+		if op.Kind != OpInsert {
+			n += op.N
+		}
+	}
+	return n
+}
+
+// Apply executes an Op sequence against doc, returning the resulting text.
+func Apply(doc string, ops []Op) (string, error) {
+	var out []byte
+	pos := 0
+	for _, op := range ops {
+		switch op.Kind {
+		case OpRetain:
+			if pos+op.N > len(doc) {
+				return "", fmt.Errorf("collab: retain past end of document (pos=%d n=%d len=%d)", pos, op.N, len(doc))
+			}
+			out = append(out, doc[pos:pos+op.N]...)
+			pos += op.N
+		case OpInsert:
+			out = append(out, op.Text...)
+		case OpDelete:
+			if pos+op.N > len(doc) {
+				return "", fmt.Errorf("collab: delete past end of document (pos=%d n=%d len=%d)", pos, op.N, len(doc))
+			}
+			pos += op.N
+		default:
+			return "", fmt.Errorf("collab: unknown op kind %q", op.Kind)
+		}
+	}
+	out = append(out, doc[pos:]...)
+	return string(out), nil
+}
+
+// Transform resolves two concurrent Op sequences that were both generated
+// against the same base document, returning a' (a transformed to apply
+// after b) and b' (b transformed to apply after a), such that
+// apply(apply(doc, a), b') == apply(apply(doc, b), a').
+//
+// Ties between two concurrent inserts at the same position are broken in
+// favor of a — i.e. a's insert is placed first. This is the
+// standard convention as long as it is applied consistently server-side.
+func Transform(a, b []Op) ([]Op, []Op, error) {
+	if spanLength(a) != spanLength(b) {
+		return nil, nil, errors.New("collab: ops do not cover the same base document length")
+	}
+
+	var aPrime, bPrime []Op
+	ai, bi := cursor{ops: a}, cursor{ops: b}
+
+	for !ai.done() || !bi.done() {
+		switch {
+		case ai.isInsert():
+			op := ai.take(opLen(ai.current()))
+			aPrime = append(aPrime, op)
+			bPrime = append(bPrime, Op{Kind: OpRetain, N: opLen(op)})
+		case bi.isInsert():
+			op := bi.take(opLen(bi.current()))
+			bPrime = append(bPrime, op)
+			aPrime = append(aPrime, Op{Kind: OpRetain, N: opLen(op)})
+		case ai.done() || bi.done():
+			return nil, nil, errors.New("collab: op sequences diverge before covering the document")
+		default:
+			n := min(ai.remaining(), bi.remaining())
+			aOp, bOp := ai.take(n), bi.take(n)
+			switch {
+			case aOp.Kind == OpDelete && bOp.Kind == OpDelete:
+				// Both delete the same span: nothing to replay either side.
+			case aOp.Kind == OpDelete:
+				aPrime = append(aPrime, Op{Kind: OpDelete, N: n})
+			case bOp.Kind == OpDelete:
+				bPrime = append(bPrime, Op{Kind: OpDelete, N: n})
+			default:
+				aPrime = append(aPrime, Op{Kind: OpRetain, N: n})
+				bPrime = append(bPrime, Op{Kind: OpRetain, N: n})
+			}
+		}
+	}
+	return compact(aPrime), compact(bPrime), nil
+}
+
+// cursor walks an Op sequence, letting Transform consume partial ops
+// (e.g. retain 10 of a 30-char retain) without mutating the original.
+type cursor struct {
+	ops []Op
+	idx int
+	off int // characters of ops[idx] already consumed
+}
+
+func (c *cursor) done() bool { return c.idx >= len(c.ops) }
+
+func (c *cursor) current() Op { return c.ops[c.idx] }
+
+func (c *cursor) isInsert() bool { return !c.done() && c.current().Kind == OpInsert }
+
+// remaining returns how many characters are left in the current op.
+func (c *cursor) remaining() int {
+	if c.done() {
+		return 0
+	}
+	return opLen(c.current()) - c.off
+}
+
+// take consumes n characters (or the whole op, for Insert) and advances.
+func (c *cursor) take(n int) Op {
+	op := c.current()
+	var piece Op
+	switch op.Kind {
+	case OpInsert:
+		piece = Op{Kind: OpInsert, Text: op.Text}
+		c.idx++
+		c.off = 0
+		return piece
+	default:
+		piece = Op{Kind: op.Kind, N: n}
+	}
+	c.off += n
+	if c.off >= opLen(op) {
+		c.idx++
+		c.off = 0
+	}
+	return piece
+}
+
+func compact(ops []Op) []Op {
+	var out []Op
+	for _, op := range ops {
+		if (op.Kind == OpRetain || op.Kind == OpDelete) && op.N == 0 {
+			continue
+		}
+		if len(out) > 0 && out[len(out)-1].Kind == op.Kind && op.Kind != OpInsert {
+			out[len(out)-1].N += op.N
+			continue
+		}
+		out = append(out, op)
+	}
+	return out
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+package collab
+
+import "testing"
+
+func TestApplyRetainInsertDelete(t *testing.T) {
+	got, err := Apply("hello world", []Op{
+		{Kind: OpRetain, N: 6},
+		{Kind: OpInsert, Text: "there "},
+		{Kind: OpDelete, N: 5},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got != "hello there " {
+		t.Fatalf("got %q, want %q", got, "hello there ")
+	}
+}
+
+func TestApplyRetainPastEndIsError(t *testing.T) {
+	if _, err := Apply("abc", []Op{{Kind: OpRetain, N: 10}}); err == nil {
+		t.Fatal("expected error retaining past end of document")
+	}
+}
+
+func TestApplyDeletePastEndIsError(t *testing.T) {
+	if _, err := Apply("abc", []Op{{Kind: OpDelete, N: 10}}); err == nil {
+		t.Fatal("expected error deleting past end of document")
+	}
+}
+
+// assertConverges checks Transform's documented invariant:
+// apply(apply(doc, a), b') == apply(apply(doc, b), a').
+func assertConverges(t *testing.T, doc string, a, b []Op) string {
+	t.Helper()
+
+	aPrime, bPrime, err := Transform(a, b)
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+
+	viaA, err := Apply(doc, a)
+	if err != nil {
+		t.Fatalf("Apply(doc, a): %v", err)
+	}
+	viaAThenBPrime, err := Apply(viaA, bPrime)
+	if err != nil {
+		t.Fatalf("Apply(apply(doc, a), b'): %v", err)
+	}
+
+	viaB, err := Apply(doc, b)
+	if err != nil {
+		t.Fatalf("Apply(doc, b): %v", err)
+	}
+	viaBThenAPrime, err := Apply(viaB, aPrime)
+	if err != nil {
+		t.Fatalf("Apply(apply(doc, b), a'): %v", err)
+	}
+
+	if viaAThenBPrime != viaBThenAPrime {
+		t.Fatalf("transform did not converge: apply(apply(doc,a),b')=%q, apply(apply(doc,b),a')=%q", viaAThenBPrime, viaBThenAPrime)
+	}
+	return viaAThenBPrime
+}
+
+func TestTransformConcurrentInsertInsert(t *testing.T) {
+	doc := "abc"
+	// a inserts "X" after position 1; b inserts "Y" after position 2.
+	a := []Op{{Kind: OpRetain, N: 1}, {Kind: OpInsert, Text: "X"}, {Kind: OpRetain, N: 2}}
+	b := []Op{{Kind: OpRetain, N: 2}, {Kind: OpInsert, Text: "Y"}, {Kind: OpRetain, N: 1}}
+
+	got := assertConverges(t, doc, a, b)
+	if got != "aXbYc" {
+		t.Fatalf("got %q, want %q", got, "aXbYc")
+	}
+}
+
+func TestTransformConcurrentInsertDelete(t *testing.T) {
+	doc := "abcdef"
+	// a deletes "cd"; b inserts "X" right after "abc".
+	a := []Op{{Kind: OpRetain, N: 2}, {Kind: OpDelete, N: 2}, {Kind: OpRetain, N: 2}}
+	b := []Op{{Kind: OpRetain, N: 3}, {Kind: OpInsert, Text: "X"}, {Kind: OpRetain, N: 3}}
+
+	got := assertConverges(t, doc, a, b)
+	if got != "abXef" {
+		t.Fatalf("got %q, want %q", got, "abXef")
+	}
+}
+
+func TestTransformConcurrentDeleteDeleteOverlap(t *testing.T) {
+	doc := "abcdef"
+	// a deletes "cd" (index 2-3); b deletes "de" (index 3-4) — they
+	// overlap on "d".
+	a := []Op{{Kind: OpRetain, N: 2}, {Kind: OpDelete, N: 2}, {Kind: OpRetain, N: 2}}
+	b := []Op{{Kind: OpRetain, N: 3}, {Kind: OpDelete, N: 2}, {Kind: OpRetain, N: 1}}
+
+	got := assertConverges(t, doc, a, b)
+	if got != "abf" {
+		t.Fatalf("got %q, want %q", got, "abf")
+	}
+}
+
+func TestTransformRejectsMismatchedSpanLength(t *testing.T) {
+	a := []Op{{Kind: OpRetain, N: 3}}
+	b := []Op{{Kind: OpRetain, N: 4}}
+	if _, _, err := Transform(a, b); err == nil {
+		t.Fatal("expected error for ops covering different base lengths")
+	}
+}
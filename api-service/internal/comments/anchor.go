@@ -0,0 +1,43 @@
+package comments
+
+import "strings"
+
+// RecomputeAnchor relocates a comment's Anchor against content that may
+// have changed since the comment was posted. If Line still holds the
+// same text, the anchor is unchanged; otherwise it searches outward from
+// the original line number for the nearest line with matching text and
+// moves the anchor there. If no line matches, the anchor's line number is
+// clamped to the document's new bounds as a last resort.
+func RecomputeAnchor(content string, a Anchor) Anchor {
+	lines := strings.Split(content, "\n")
+	if idx := a.Line - 1; idx >= 0 && idx < len(lines) && lines[idx] == a.LineText {
+		return a
+	}
+
+	for delta := 1; delta < len(lines); delta++ {
+		for _, idx := range [2]int{a.Line - 1 - delta, a.Line - 1 + delta} {
+			if idx >= 0 && idx < len(lines) && lines[idx] == a.LineText {
+				moved := a
+				moved.Line = idx + 1
+				return moved
+			}
+		}
+	}
+
+	// strings.Split leaves a trailing "" element for content ending in a
+	// newline (the common case); that's not a real line, so it shouldn't
+	// count toward the clamp bound.
+	maxLine := len(lines)
+	if maxLine > 1 && lines[maxLine-1] == "" {
+		maxLine--
+	}
+
+	clamped := a
+	switch {
+	case clamped.Line < 1:
+		clamped.Line = 1
+	case clamped.Line > maxLine:
+		clamped.Line = maxLine
+	}
+	return clamped
+}
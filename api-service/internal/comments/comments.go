@@ -0,0 +1,123 @@
+// Package comments implements line-anchored review comments on LaTeX
+// sources: threaded replies, resolve/unresolve, and a pluggable Store so
+// the in-memory default can later be swapped for a Mongo- or
+// SQLite-backed implementation without touching the HTTP layer.
+package comments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Anchor locates a comment in a document's source: Line/Col/Len are a
+// 1-indexed line, 0-indexed column, and character length, and LineText is
+// the exact text of Line at the time the comment was posted, used to
+// relocate the comment (see RecomputeAnchor) once the document has since
+// been edited.
+type Anchor struct {
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Len      int    `json:"len"`
+	LineText string `json:"lineText"`
+}
+
+// Comment is one review comment or reply.
+type Comment struct {
+	ID          string    `json:"id"`
+	DocumentID  string    `json:"documentId"`
+	Author      string    `json:"author"`
+	PostedAt    time.Time `json:"postedAt"`
+	Anchor      Anchor    `json:"anchor"`
+	Message     string    `json:"message"`
+	Resolved    bool      `json:"resolved"`
+	ParentID    string    `json:"parentId,omitempty"`
+	ContentHash string    `json:"-"`
+}
+
+// Store persists comments. MemStore is the default, in-process
+// implementation used when the service starts with no database
+// configured; a Mongo- or SQLite-backed Store satisfying this same
+// interface can be swapped in without changing the HTTP handlers.
+type Store interface {
+	Create(ctx context.Context, c Comment) (Comment, error)
+	Get(ctx context.Context, id string) (Comment, error)
+	ListByDocument(ctx context.Context, documentID string) ([]Comment, error)
+	Update(ctx context.Context, c Comment) error
+	Delete(ctx context.Context, id string) error
+}
+
+// MemStore is a process-local, mutex-guarded Store.
+type MemStore struct {
+	mu     sync.Mutex
+	byID   map[string]Comment
+	nextID int
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{byID: map[string]Comment{}}
+}
+
+func (s *MemStore) Create(ctx context.Context, c Comment) (Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.ID == "" {
+		s.nextID++
+		c.ID = fmt.Sprintf("c%d", s.nextID)
+	}
+	s.byID[c.ID] = c
+	return c, nil
+}
+
+func (s *MemStore) Get(ctx context.Context, id string) (Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.byID[id]
+	if !ok {
+		return Comment{}, fmt.Errorf("comments: no such comment %q", id)
+	}
+	return c, nil
+}
+
+func (s *MemStore) ListByDocument(ctx context.Context, documentID string) ([]Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Comment
+	for _, c := range s.byID {
+		if c.DocumentID == documentID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemStore) Update(ctx context.Context, c Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[c.ID]; !ok {
+		return fmt.Errorf("comments: no such comment %q", c.ID)
+	}
+	s.byID[c.ID] = c
+	return nil
+}
+
+func (s *MemStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[id]; !ok {
+		return fmt.Errorf("comments: no such comment %q", id)
+	}
+	delete(s.byID, id)
+	return nil
+}
+
+// HashContent returns the content hash stored on a Comment at creation
+// time, so a reader can tell cheaply whether a document has changed since
+// without comparing line text.
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
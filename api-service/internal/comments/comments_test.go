@@ -0,0 +1,81 @@
+package comments
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemStoreCreateListUpdateDelete(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemStore()
+
+	c, err := s.Create(ctx, Comment{DocumentID: "p1:main.tex", Message: "looks off"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if c.ID == "" {
+		t.Fatal("expected Create to assign an ID")
+	}
+
+	reply, err := s.Create(ctx, Comment{DocumentID: "p1:main.tex", Message: "fixed", ParentID: c.ID})
+	if err != nil {
+		t.Fatalf("Create reply: %v", err)
+	}
+
+	list, err := s.ListByDocument(ctx, "p1:main.tex")
+	if err != nil {
+		t.Fatalf("ListByDocument: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d comments, want 2", len(list))
+	}
+
+	got, err := s.Get(ctx, reply.ID)
+	if err != nil || got.ParentID != c.ID {
+		t.Fatalf("Get reply = %#v, err %v", got, err)
+	}
+
+	c.Resolved = true
+	if err := s.Update(ctx, c); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = s.Get(ctx, c.ID)
+	if err != nil || !got.Resolved {
+		t.Fatalf("expected resolved comment, got %#v, err %v", got, err)
+	}
+
+	if err := s.Delete(ctx, reply.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, reply.ID); err == nil {
+		t.Fatal("expected Get after Delete to fail")
+	}
+}
+
+func TestRecomputeAnchorUnchangedWhenLineMatches(t *testing.T) {
+	content := "one\ntwo\nthree\n"
+	a := Anchor{Line: 2, Col: 0, Len: 3, LineText: "two"}
+	got := RecomputeAnchor(content, a)
+	if got != a {
+		t.Errorf("got %#v, want unchanged %#v", got, a)
+	}
+}
+
+func TestRecomputeAnchorFollowsMovedLine(t *testing.T) {
+	// "two" moved from line 2 to line 4 after two lines were inserted above it.
+	content := "one\nzero-point-five\ninserted\ntwo\nthree\n"
+	a := Anchor{Line: 2, Col: 0, Len: 3, LineText: "two"}
+	got := RecomputeAnchor(content, a)
+	if got.Line != 4 {
+		t.Errorf("got line %d, want 4", got.Line)
+	}
+}
+
+func TestRecomputeAnchorClampsWhenLineTextGone(t *testing.T) {
+	content := "one\n"
+	a := Anchor{Line: 5, Col: 0, Len: 3, LineText: "nonexistent"}
+	got := RecomputeAnchor(content, a)
+	if got.Line != 1 {
+		t.Errorf("got line %d, want clamped to 1", got.Line)
+	}
+}
@@ -0,0 +1,43 @@
+// Package compile implements the sandboxed LaTeX compilation worker:
+// it watches queued jobs, runs pdflatex/xelatex/lualatex/latexmk inside
+// an isolated per-job working directory, enforces resource limits, and
+// reports status/log artifacts back to the caller.
+package compile
+
+import "time"
+
+// State is a compile job's lifecycle stage.
+type State string
+
+const (
+	StateQueued  State = "queued"
+	StateRunning State = "running"
+	StateSuccess State = "success"
+	StateFailed  State = "failed"
+	StateTimeout State = "timeout"
+)
+
+// Job describes a single compile request as it sits on the queue.
+type Job struct {
+	JobID     string `json:"jobId"`
+	ProjectID string `json:"projectId"`
+	EntryFile string `json:"entryFile"`
+	Engine    string `json:"engine"`
+	Revision  string `json:"revision"`
+}
+
+// Status is the on-disk record written to compile/status/<jobID>.json and
+// polled by watchJobStatus. It is also what Worker updates as a job moves
+// through the queued -> running -> success|failed|timeout lifecycle.
+type Status struct {
+	State      State  `json:"state"`
+	ExitCode   int    `json:"exitCode,omitempty"`
+	DurationMs int64  `json:"durationMs,omitempty"`
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func nowRFC3339() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,154 @@
+package compile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Queue is the backend jobs are pushed onto and workers pull from.
+// Selecting COMPILE_BACKEND=redis lets multiple Worker processes consume
+// the same stream instead of polling a shared filesystem.
+type Queue interface {
+	// Push enqueues a job for processing.
+	Push(ctx context.Context, job Job) error
+	// Pop takes the next job, reporting false when none is available.
+	// FSQueue returns at once; RedisQueue waits up to one second.
+	Pop(ctx context.Context) (Job, bool, error)
+}
+
+// NewQueue selects a Queue implementation from COMPILE_BACKEND (fs|redis).
+// projectsRoot is the directory containing one subdirectory per project
+// (LATEX_FILES_DIR), matching the <root>/<projectID>/compile/queue layout
+// the rest of the service uses.
+func NewQueue(backend, projectsRoot, redisAddr, redisKey string) (Queue, error) {
+	switch backend {
+	case "redis":
+		return NewRedisQueue(redisAddr, redisKey), nil
+	case "", "fs":
+		return NewFSQueue(projectsRoot), nil
+	default:
+		return nil, fmt.Errorf("compile: unknown COMPILE_BACKEND %q", backend)
+	}
+}
+
+// FSQueue watches each project's compile/queue/<jobID>.json files,
+// matching the original filesystem-only behavior of enqueueJob.
+type FSQueue struct {
+	projectsRoot string
+}
+
+func NewFSQueue(projectsRoot string) *FSQueue {
+	return &FSQueue{projectsRoot: projectsRoot}
+}
+
+func (q *FSQueue) queueDir(projectID string) string {
+	return filepath.Join(q.projectsRoot, projectID, "compile", "queue")
+}
+
+func (q *FSQueue) Push(ctx context.Context, job Job) error {
+	dir := q.queueDir(job.ProjectID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, job.JobID+".json"), b, 0o644)
+}
+
+// Pop scans every known project's queue directory and takes the queued
+// job file whose path sorts first (by project ID, then job ID), removing
+// it; this is not necessarily the job pushed earliest. It does not block;
+// callers poll on a ticker.
+func (q *FSQueue) Pop(ctx context.Context) (Job, bool, error) {
+	projects, err := os.ReadDir(q.projectsRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Job{}, false, nil
+		}
+		return Job{}, false, err
+	}
+	var candidates []string
+	for _, p := range projects {
+		if !p.IsDir() {
+			continue
+		}
+		dir := q.queueDir(p.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() && filepath.Ext(e.Name()) == ".json" {
+				candidates = append(candidates, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return Job{}, false, nil
+	}
+	sort.Strings(candidates)
+	path := candidates[0]
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Job{}, false, err
+	}
+	var job Job
+	if err := json.Unmarshal(b, &job); err != nil {
+		os.Remove(path)
+		return Job{}, false, err
+	}
+	os.Remove(path)
+	return job, true, nil
+}
+
+// RedisQueue pushes/pops jobs from a Redis LIST, allowing several Worker
+// processes on different hosts to share one compile backlog.
+type RedisQueue struct {
+	client *redis.Client
+	key    string
+}
+
+func NewRedisQueue(addr, key string) *RedisQueue {
+	if key == "" {
+		key = "compile:queue"
+	}
+	return &RedisQueue{
+		client: redis.NewClient(&redis.Options{Addr: addr}),
+		key:    key,
+	}
+}
+
+func (q *RedisQueue) Push(ctx context.Context, job Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.client.LPush(ctx, q.key, b).Err()
+}
+
+func (q *RedisQueue) Pop(ctx context.Context) (Job, bool, error) {
+	res, err := q.client.BRPop(ctx, time.Second, q.key).Result()
+	if err == redis.Nil {
+		return Job{}, false, nil
+	}
+	if err != nil {
+		return Job{}, false, err
+	}
+	if len(res) != 2 {
+		return Job{}, false, nil
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(res[1]), &job); err != nil {
+		return Job{}, false, err
+	}
+	return job, true, nil
+}
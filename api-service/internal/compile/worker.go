@@ -0,0 +1,371 @@
+package compile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Limits bounds a single compile job's resource usage.
+type Limits struct {
+	Wall    time.Duration // wall-clock budget before the job is killed
+	CPUSecs uint64        // RLIMIT_CPU, in seconds
+	MemMB   uint64        // RLIMIT_AS, in megabytes
+}
+
+// DefaultLimits mirrors what a single pdflatex pass on a typical document
+// needs, with headroom for a bibliography rerun.
+var DefaultLimits = Limits{
+	Wall:    60 * time.Second,
+	CPUSecs: 30,
+	MemMB:   1024,
+}
+
+var engineBinaries = map[string]string{
+	"pdflatex": "pdflatex",
+	"xelatex":  "xelatex",
+	"lualatex": "lualatex",
+	"latexmk":  "latexmk",
+	"tectonic": "tectonic",
+}
+
+// maxRerunPasses bounds the latexmk-style "rerun until cross-references
+// settle" loop in runJob, so a document that never stops asking for a
+// rerun can't hang a job indefinitely.
+const maxRerunPasses = 3
+
+// Worker pulls jobs off a Queue, runs them in an isolated working
+// directory under the project's compile/working, and records progress
+// under compile/status and compile/logs so watchJobStatus (and any other
+// consumer) can follow along.
+//
+// FetchFiles/PublishFile let the caller back project source and compiled
+// artifacts with whatever Storage implementation it's configured with
+// (local disk or S3), so the API and its compile workers don't need to
+// share a filesystem. When left nil, the worker falls back to copying
+// ProjectRoot(projectID) directly off local disk.
+type Worker struct {
+	Queue       Queue
+	ProjectRoot func(projectID string) string // e.g. api-service's projectDir
+	Limits      Limits
+	FetchFiles  func(ctx context.Context, projectID, destDir string) error
+	PublishFile func(ctx context.Context, projectID, key, localPath string) error
+	OnSuccess   func(ctx context.Context, projectID string) // e.g. recording a version snapshot
+
+	mu      sync.Mutex
+	running map[string]*exec.Cmd // jobID -> in-flight process, for cancellation
+}
+
+func NewWorker(q Queue, projectRoot func(string) string, limits Limits) *Worker {
+	return &Worker{
+		Queue:       q,
+		ProjectRoot: projectRoot,
+		Limits:      limits,
+		running:     map[string]*exec.Cmd{},
+	}
+}
+
+// Run polls the queue until ctx is canceled, executing one job at a time.
+// Callers typically launch this in its own goroutine per worker process.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job, ok, err := w.Queue.Pop(ctx)
+			if err != nil || !ok {
+				continue
+			}
+			w.runJob(ctx, job)
+		}
+	}
+}
+
+// Cancel SIGTERMs a running job's process, if one is currently executing.
+func (w *Worker) Cancel(jobID string) bool {
+	w.mu.Lock()
+	cmd, ok := w.running[jobID]
+	w.mu.Unlock()
+	if !ok || cmd.Process == nil {
+		return false
+	}
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+	return true
+}
+
+func (w *Worker) runJob(ctx context.Context, job Job) {
+	root := w.ProjectRoot(job.ProjectID)
+	statusPath := filepath.Join(root, "compile", "status", job.JobID+".json")
+	logPath := filepath.Join(root, "compile", "logs", job.JobID+".txt")
+
+	workDir, err := os.MkdirTemp(filepath.Join(root, "compile", "working"), job.JobID+"-")
+	if err != nil {
+		w.writeStatus(statusPath, Status{State: StateFailed, Error: err.Error(), FinishedAt: nowRFC3339()})
+		return
+	}
+	defer os.RemoveAll(workDir)
+
+	fetch := w.FetchFiles
+	if fetch == nil {
+		fetch = func(_ context.Context, projectID, destDir string) error {
+			return copyProjectTree(w.ProjectRoot(projectID), destDir)
+		}
+	}
+	if err := fetch(ctx, job.ProjectID, workDir); err != nil {
+		w.writeStatus(statusPath, Status{State: StateFailed, Error: err.Error(), FinishedAt: nowRFC3339()})
+		return
+	}
+
+	started := time.Now()
+	w.writeStatus(statusPath, Status{State: StateRunning, StartedAt: started.UTC().Format(time.RFC3339)})
+
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		w.writeStatus(statusPath, Status{State: StateFailed, Error: err.Error(), FinishedAt: nowRFC3339()})
+		return
+	}
+	defer logFile.Close()
+
+	runCtx, cancel := context.WithTimeout(ctx, w.Limits.Wall)
+	defer cancel()
+
+	bin := engineBinaries[job.Engine]
+	if bin == "" {
+		bin = "pdflatex"
+	}
+	entry := job.EntryFile
+	if entry == "" {
+		entry = "main.tex"
+	}
+
+	exitCode, runErr := w.runEngine(runCtx, bin, entry, workDir, logFile)
+
+	if needsBibliography(workDir, entry) && runErr == nil && exitCode == 0 {
+		biberExit, biberErr := w.runEngine(runCtx, "biber", trimTexExt(entry), workDir, logFile)
+		if biberErr != nil || biberExit != 0 {
+			fmt.Fprintf(logFile, "\nbibliography pass failed (exit %d): %v\n", biberExit, biberErr)
+		}
+		exitCode, runErr = w.runEngine(runCtx, bin, entry, workDir, logFile)
+	}
+
+	// latexmk-style rerun: pdflatex prints "Rerun to get cross-references
+	// right" (or flags that labels may have changed) when the .aux/.toc it
+	// just wrote would change the output, so keep compiling until that
+	// settles or we hit a sane pass limit.
+	enginePassLog := filepath.Join(workDir, trimTexExt(entry)+".log")
+	for pass := 0; pass < maxRerunPasses && runErr == nil && exitCode == 0 && logNeedsRerun(enginePassLog); pass++ {
+		exitCode, runErr = w.runEngine(runCtx, bin, entry, workDir, logFile)
+	}
+
+	finished := time.Now()
+	status := Status{
+		ExitCode:   exitCode,
+		DurationMs: finished.Sub(started).Milliseconds(),
+		StartedAt:  started.UTC().Format(time.RFC3339),
+		FinishedAt: finished.UTC().Format(time.RFC3339),
+	}
+
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		status.State = StateTimeout
+		status.Error = "compile exceeded wall-clock limit"
+	case runErr != nil:
+		status.State = StateFailed
+		status.Error = runErr.Error()
+	case exitCode != 0:
+		status.State = StateFailed
+		status.Error = fmt.Sprintf("%s exited with status %d", bin, exitCode)
+	default:
+		status.State = StateSuccess
+		if pdf := filepath.Join(workDir, trimTexExt(entry)+".pdf"); fileExists(pdf) {
+			if w.PublishFile != nil {
+				if err := w.PublishFile(ctx, job.ProjectID, "output.pdf", pdf); err != nil {
+					status.State = StateFailed
+					status.Error = fmt.Sprintf("failed to publish output.pdf: %v", err)
+				}
+			} else {
+				os.Rename(pdf, filepath.Join(root, "output.pdf"))
+			}
+		}
+		if status.State == StateSuccess && w.OnSuccess != nil {
+			w.OnSuccess(ctx, job.ProjectID)
+		}
+	}
+	w.writeStatus(statusPath, status)
+}
+
+// runEngine executes one compiler pass inside workDir with CPU/memory
+// rlimits applied to the child process, streaming combined output to log.
+func (w *Worker) runEngine(ctx context.Context, bin, entry, workDir string, logw io.Writer) (int, error) {
+	cmd := exec.CommandContext(ctx, bin, engineArgs(bin, entry)...)
+	cmd.Dir = workDir
+	cmd.Stdout = logw
+	cmd.Stderr = logw
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// workDir is "<jobID>-<random suffix>" (os.MkdirTemp in runJob).
+	jobID := filepath.Base(workDir)
+	if i := strings.LastIndex(jobID, "-"); i >= 0 {
+		jobID = jobID[:i]
+	}
+	w.mu.Lock()
+	w.running[jobID] = cmd
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.running, jobID)
+		w.mu.Unlock()
+	}()
+
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+	applyRlimits(cmd.Process.Pid, w.Limits)
+
+	err := cmd.Wait()
+	if err == nil {
+		return 0, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	return -1, err
+}
+
+// engineArgs builds the CLI invocation for bin, since tectonic takes a
+// different flag set than the pdflatex-family engines, and biber (run by
+// runJob's bibliography pass) takes only the document's base name.
+func engineArgs(bin, entry string) []string {
+	if bin == "biber" {
+		return []string{entry}
+	}
+	if bin == "tectonic" {
+		return []string{"--keep-logs", entry}
+	}
+	return []string{"-interaction=nonstopmode", "-halt-on-error", entry}
+}
+
+// logNeedsRerun reports whether a compiler pass's log asked for another
+// pass to resolve cross-references (pdflatex/xelatex/lualatex all emit
+// one of these phrases when .aux/.toc changed enough to affect output).
+func logNeedsRerun(logPath string) bool {
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		return false
+	}
+	s := string(b)
+	return containsAny(s, "Rerun to get", "Label(s) may have changed")
+}
+
+// applyRlimits scopes CPU/memory limits to pid alone via prlimit(2).
+// syscall.Setrlimit only ever affects the calling process, which here
+// would be the API/worker server itself rather than the spawned
+// pdflatex/xelatex child — and since it sets Cur and Max to the same
+// value, a second call would only be able to lower the limit further,
+// permanently ratcheting the server down job after job.
+func applyRlimits(pid int, limits Limits) {
+	if limits.CPUSecs > 0 {
+		rlim := unix.Rlimit{Cur: limits.CPUSecs, Max: limits.CPUSecs}
+		_ = unix.Prlimit(pid, unix.RLIMIT_CPU, &rlim, nil)
+	}
+	if limits.MemMB > 0 {
+		b := limits.MemMB * 1024 * 1024
+		rlim := unix.Rlimit{Cur: b, Max: b}
+		_ = unix.Prlimit(pid, unix.RLIMIT_AS, &rlim, nil)
+	}
+}
+
+func (w *Worker) writeStatus(path string, s Status) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(path, b, 0o644)
+}
+
+func needsBibliography(workDir, entry string) bool {
+	b, err := os.ReadFile(filepath.Join(workDir, entry))
+	if err != nil {
+		return false
+	}
+	s := string(b)
+	return containsAny(s, `\bibliography{`, `\addbibresource{`)
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if len(s) >= len(sub) {
+			for i := 0; i+len(sub) <= len(s); i++ {
+				if s[i:i+len(sub)] == sub {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func trimTexExt(entry string) string {
+	if ext := filepath.Ext(entry); ext == ".tex" {
+		return entry[:len(entry)-len(ext)]
+	}
+	return entry
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// copyProjectTree copies a project's source files (everything except the
+// compile/ bookkeeping directory) into an isolated per-job working
+// directory so concurrent jobs never see each other's intermediate files.
+func copyProjectTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+		if err != nil || p == src {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if rel == "compile" || strings.HasPrefix(rel, "compile"+string(os.PathSeparator)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		_, err = io.Copy(out, in)
+		return err
+	})
+}
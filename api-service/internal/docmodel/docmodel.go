@@ -0,0 +1,106 @@
+// Package docmodel gives a LaTeX document a small structured JSON view —
+// preamble, top-level sections, and a handful of metadata fields — so
+// callers like the JSON Patch endpoint can address a document by
+// structure instead of raw character offsets. It is intentionally not a
+// full LaTeX parser: anything it doesn't recognize (nested sectioning
+// commands, environments that span the section boundary) just lands in
+// the body text of whichever section contains it.
+package docmodel
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Section is one \section{Title} and the body text up to the next
+// top-level section (or the end of the document).
+type Section struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Doc is the structured view of a document: everything before
+// \begin{document} as Preamble, metadata pulled out of it, and the
+// document body split into Sections.
+type Doc struct {
+	Preamble string            `json:"preamble"`
+	Metadata map[string]string `json:"metadata"`
+	Sections []Section         `json:"sections"`
+}
+
+var (
+	beginDocumentRE = regexp.MustCompile(`\\begin\{document\}`)
+	endDocumentRE   = regexp.MustCompile(`\\end\{document\}`)
+	sectionRE       = regexp.MustCompile(`(?m)^\\section\{([^}]*)\}[ \t]*\n?`)
+	metadataRE      = map[string]*regexp.Regexp{
+		"title":  regexp.MustCompile(`\\title\{([^}]*)\}`),
+		"author": regexp.MustCompile(`\\author\{([^}]*)\}`),
+		"date":   regexp.MustCompile(`\\date\{([^}]*)\}`),
+	}
+)
+
+// Parse builds a Doc from raw .tex source.
+func Parse(content string) Doc {
+	preamble, body := content, ""
+	if loc := beginDocumentRE.FindStringIndex(content); loc != nil {
+		preamble = content[:loc[0]]
+		body = content[loc[1]:]
+	}
+	if loc := endDocumentRE.FindStringIndex(body); loc != nil {
+		body = body[:loc[0]]
+	}
+
+	metadata := map[string]string{}
+	for key, re := range metadataRE {
+		if m := re.FindStringSubmatch(preamble); m != nil {
+			metadata[key] = m[1]
+		}
+	}
+
+	return Doc{Preamble: preamble, Metadata: metadata, Sections: splitSections(body)}
+}
+
+// splitSections breaks body at top-level \section{...} commands. Text
+// before the first \section (if any) becomes a section with an empty
+// Title.
+func splitSections(body string) []Section {
+	locs := sectionRE.FindAllStringSubmatchIndex(body, -1)
+	if len(locs) == 0 {
+		return []Section{{Title: "", Body: strings.TrimPrefix(body, "\n")}}
+	}
+
+	var sections []Section
+	if front := body[:locs[0][0]]; strings.TrimSpace(front) != "" {
+		sections = append(sections, Section{Title: "", Body: front})
+	}
+	for i, loc := range locs {
+		title := body[loc[2]:loc[3]]
+		start := loc[1]
+		end := len(body)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		sections = append(sections, Section{Title: title, Body: body[start:end]})
+	}
+	return sections
+}
+
+// Render reassembles Doc back into .tex source. It is not guaranteed to
+// byte-for-byte reproduce the input Parse was built from: in particular,
+// \end{document} is always re-added at the end, and anything that followed
+// it in the input is dropped, since Parse does not keep it.
+func (d Doc) Render() string {
+	var b strings.Builder
+	b.WriteString(d.Preamble)
+	b.WriteString("\\begin{document}\n")
+	for _, s := range d.Sections {
+		if s.Title != "" {
+			b.WriteString("\\section{")
+			b.WriteString(s.Title)
+			b.WriteString("}\n")
+		}
+		b.WriteString(s.Body)
+	}
+	b.WriteString("\\end{document}\n")
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package docmodel
+
+import "testing"
+
+func TestParseSplitsPreambleMetadataAndSections(t *testing.T) {
+	src := "\\documentclass{article}\n\\title{My Paper}\n\\author{Ada}\n" +
+		"\\begin{document}\n\\section{Intro}\nHello.\n\\section{Conclusion}\nBye.\n\\end{document}\n"
+
+	d := Parse(src)
+
+	if d.Metadata["title"] != "My Paper" || d.Metadata["author"] != "Ada" {
+		t.Fatalf("unexpected metadata: %#v", d.Metadata)
+	}
+	if len(d.Sections) != 2 {
+		t.Fatalf("got %d sections, want 2: %#v", len(d.Sections), d.Sections)
+	}
+	if d.Sections[0].Title != "Intro" || d.Sections[0].Body != "Hello.\n" {
+		t.Errorf("section 0 = %#v", d.Sections[0])
+	}
+	if d.Sections[1].Title != "Conclusion" || d.Sections[1].Body != "Bye.\n" {
+		t.Errorf("section 1 = %#v", d.Sections[1])
+	}
+}
+
+func TestParseWithoutSectionsKeepsWholeBodyAsOneSection(t *testing.T) {
+	src := "\\documentclass{article}\n\\begin{document}\nJust text.\n\\end{document}\n"
+	d := Parse(src)
+	if len(d.Sections) != 1 || d.Sections[0].Title != "" || d.Sections[0].Body != "Just text.\n" {
+		t.Fatalf("unexpected sections: %#v", d.Sections)
+	}
+}
+
+func TestRenderRoundTripsSections(t *testing.T) {
+	d := Doc{
+		Preamble: "\\documentclass{article}\n",
+		Sections: []Section{
+			{Title: "Intro", Body: "Hello.\n"},
+			{Title: "Conclusion", Body: "Bye.\n"},
+		},
+	}
+	got := d.Render()
+	want := "\\documentclass{article}\n\\begin{document}\n\\section{Intro}\nHello.\n\\section{Conclusion}\nBye.\n\\end{document}\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package format
+
+import "strings"
+
+// alignColumns pads the "&"-separated cells of a tabular/align-family row
+// group so each column starts at the same position, then re-indents the
+// result at indent nesting levels. Rows are expected to already have had
+// their trailing "\\" (if any) kept as part of the last cell.
+func alignColumns(rows []string, indent int) []string {
+	cells := make([][]string, len(rows))
+	width := 0
+	for i, row := range rows {
+		cells[i] = strings.Split(row, "&")
+		if len(cells[i]) > width {
+			width = len(cells[i])
+		}
+	}
+
+	colWidth := make([]int, width)
+	for _, rowCells := range cells {
+		for c, cell := range rowCells {
+			if c == len(rowCells)-1 {
+				continue // last cell isn't padded; it just trails to \\
+			}
+			cell = strings.TrimSpace(cell)
+			if len(cell) > colWidth[c] {
+				colWidth[c] = len(cell)
+			}
+		}
+	}
+
+	prefix := indentFor(indent)
+	out := make([]string, len(rows))
+	for i, rowCells := range cells {
+		var b strings.Builder
+		b.WriteString(prefix)
+		for c, cell := range rowCells {
+			cell = strings.TrimSpace(cell)
+			if c > 0 {
+				b.WriteString(" & ")
+			}
+			if c < len(rowCells)-1 {
+				b.WriteString(cell)
+				b.WriteString(strings.Repeat(" ", colWidth[c]-len(cell)))
+			} else {
+				b.WriteString(cell)
+			}
+		}
+		out[i] = b.String()
+	}
+	return out
+}
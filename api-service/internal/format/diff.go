@@ -0,0 +1,90 @@
+package format
+
+// linesToEdits computes the minimal set of whole-line TextEdits that turn
+// original into formatted, via an LCS-based line diff. Runs of changed
+// lines are merged into a single edit that replaces them with the
+// corresponding formatted lines (a pure deletion is an edit with empty
+// NewText; a pure insertion is a zero-width range at the insertion point).
+func linesToEdits(original, formatted []string) []TextEdit {
+	n, m := len(original), len(formatted)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if original[i] == formatted[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] >= dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ' equal, '-' removed, '+' added
+		text string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case original[i] == formatted[j]:
+			ops = append(ops, op{' ', original[i]})
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			ops = append(ops, op{'-', original[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', formatted[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, op{'-', original[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, op{'+', formatted[j]})
+	}
+
+	var edits []TextEdit
+	origLine := 0
+	k := 0
+	for k < len(ops) {
+		if ops[k].kind == ' ' {
+			origLine++
+			k++
+			continue
+		}
+		startOrig := origLine
+		var removed, added []string
+		for k < len(ops) && ops[k].kind != ' ' {
+			if ops[k].kind == '-' {
+				removed = append(removed, ops[k].text)
+				origLine++
+			} else {
+				added = append(added, ops[k].text)
+			}
+			k++
+		}
+		edits = append(edits, TextEdit{
+			Range:   Range{StartLine: startOrig, EndLine: startOrig + len(removed)},
+			NewText: joinWithTrailingNewline(added),
+		})
+	}
+	return edits
+}
+
+func joinWithTrailingNewline(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	s := ""
+	for _, l := range lines {
+		s += l + "\n"
+	}
+	return s
+}
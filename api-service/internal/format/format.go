@@ -0,0 +1,121 @@
+// Package format implements latexfmt, a canonical formatter for .tex
+// source: two-space indentation per \begin{...} nesting level, single
+// blank lines between paragraphs, aligned "&" columns inside
+// tabular/align-family environments, and byte-for-byte preservation of
+// verbatim/lstlisting regions. It reports changes as minimal TextEdits,
+// the way gopls' Format does for Go source, so an editor can apply a
+// diff instead of replacing the whole buffer.
+package format
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Range identifies a span of a document by zero-indexed line/column,
+// columns counted in runes. An End equal to Start (both zero) means
+// "the whole document" — Format treats that as shorthand rather than an
+// empty range, since callers formatting on save rarely know the
+// document's exact extent up front.
+type Range struct {
+	StartLine, StartCol int
+	EndLine, EndCol      int
+}
+
+func (r Range) isWholeDocument() bool {
+	return r == Range{}
+}
+
+// TextEdit replaces the content at Range with NewText.
+type TextEdit struct {
+	Range   Range
+	NewText string
+}
+
+// Config controls when/how formatting runs; it's deliberately small since
+// the formatter itself is not configurable beyond what canonical style
+// requires.
+type Config struct {
+	// FormatOnSave mirrors the editor's "format on save" toggle; it has
+	// no effect on Format itself; callers read it before deciding to
+	// invoke Format at all.
+	FormatOnSave bool
+}
+
+// DefaultConfig matches the editor's out-of-the-box behavior.
+var DefaultConfig = Config{FormatOnSave: true}
+
+// Format parses src as .tex source and returns the minimal set of edits
+// that rewrite it into canonical style within rng (or the whole document,
+// if rng is the zero Range). ctx is accepted for symmetry with the rest
+// of the API (e.g. a future worker pool or cancellation) but the current
+// implementation is pure and never blocks on it.
+func Format(ctx context.Context, src []byte, rng Range) ([]TextEdit, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	original := splitLines(string(src))
+	formatted := formatLines(original)
+
+	edits := linesToEdits(original, formatted)
+	if rng.isWholeDocument() {
+		return edits, nil
+	}
+	return clampEdits(edits, rng), nil
+}
+
+// Apply splices edits (as returned by Format, against the same src) into
+// src and returns the resulting text. Edits are assumed non-overlapping,
+// whole-line ranges, which is all Format ever produces.
+func Apply(src []byte, edits []TextEdit) []byte {
+	if len(edits) == 0 {
+		return src
+	}
+	lines := splitLines(string(src))
+	sorted := make([]TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Range.StartLine < sorted[j].Range.StartLine })
+
+	var out []string
+	cursor := 0
+	for _, e := range sorted {
+		out = append(out, lines[cursor:e.Range.StartLine]...)
+		if e.NewText != "" {
+			out = append(out, splitLines(e.NewText)...)
+		}
+		cursor = e.Range.EndLine
+	}
+	out = append(out, lines[cursor:]...)
+
+	var b strings.Builder
+	for _, l := range out {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// clampEdits drops edits that fall entirely outside rng; it's a coarse
+// line-based filter (Format only ever produces whole-line edits), good
+// enough for "format just the selection" without rewriting diff math.
+func clampEdits(edits []TextEdit, rng Range) []TextEdit {
+	var out []TextEdit
+	for _, e := range edits {
+		if e.Range.EndLine < rng.StartLine || e.Range.StartLine > rng.EndLine {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package format
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFormatIndentsNestedEnvironments(t *testing.T) {
+	src := "\\begin{document}\n\\begin{itemize}\n\\item one\n\\end{itemize}\n\\end{document}\n"
+	want := "\\begin{document}\n  \\begin{itemize}\n    \\item one\n  \\end{itemize}\n\\end{document}\n"
+
+	edits, err := Format(context.Background(), []byte(src), Range{})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	got := string(Apply([]byte(src), edits))
+	if got != want {
+		t.Errorf("Format() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatCollapsesBlankLines(t *testing.T) {
+	src := "\\section{A}\n\n\n\ntext\n"
+	want := "\\section{A}\n\ntext\n"
+
+	edits, err := Format(context.Background(), []byte(src), Range{})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	got := string(Apply([]byte(src), edits))
+	if got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatPreservesVerbatim(t *testing.T) {
+	src := "\\begin{verbatim}\n   keep    this\texactly\n\\end{verbatim}\n"
+	edits, err := Format(context.Background(), []byte(src), Range{})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	got := string(Apply([]byte(src), edits))
+	if !strings.Contains(got, "   keep    this\texactly\n") {
+		t.Errorf("verbatim body was reformatted: %q", got)
+	}
+}
+
+func TestFormatAlignsTabularColumns(t *testing.T) {
+	src := "\\begin{tabular}{ll}\na & bb \\\\\nccc & d \\\\\n\\end{tabular}\n"
+	edits, err := Format(context.Background(), []byte(src), Range{})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	got := string(Apply([]byte(src), edits))
+	want := "\\begin{tabular}{ll}\n  a   & bb \\\\\n  ccc & d \\\\\n\\end{tabular}\n"
+	if got != want {
+		t.Errorf("Format() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	src := "\\begin{document}\n\\section{A}\ntext\n\\end{document}\n"
+	edits, err := Format(context.Background(), []byte(src), Range{})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	once := Apply([]byte(src), edits)
+
+	edits2, err := Format(context.Background(), once, Range{})
+	if err != nil {
+		t.Fatalf("Format (second pass): %v", err)
+	}
+	if len(edits2) != 0 {
+		t.Errorf("formatting already-formatted source produced edits: %+v", edits2)
+	}
+}
@@ -0,0 +1,128 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	beginRE = regexp.MustCompile(`^\\begin\{([^}]+)\}`)
+	endRE   = regexp.MustCompile(`^\\end\{([^}]+)\}`)
+)
+
+// verbatimEnvs preserve their body byte-for-byte: no indentation, no
+// blank-line collapsing, no column alignment, and (since LaTeX itself
+// doesn't interpret markup inside them) no nested \begin/\end tracking.
+var verbatimEnvs = map[string]bool{
+	"verbatim":   true,
+	"verbatim*":  true,
+	"lstlisting": true,
+	"minted":     true,
+}
+
+// alignEnvs get their "&" columns aligned; everything else just gets
+// indented.
+var alignEnvs = map[string]bool{
+	"tabular":  true,
+	"tabular*": true,
+	"align":    true,
+	"align*":   true,
+	"aligned":  true,
+	"matrix":   true,
+	"pmatrix":  true,
+	"bmatrix":  true,
+	"array":    true,
+}
+
+const indentUnit = "  "
+
+// formatLines re-indents lines into canonical style (it does not wrap),
+// tracking \begin{...}/\end{...} nesting to choose each line's indent and
+// to know when it's inside a verbatim or "&"-aligned environment.
+func formatLines(lines []string) []string {
+	var out []string
+	depth := 0
+	inVerbatim := "" // the verbatim env name we're currently inside of, or ""
+	var alignBlock []string
+	alignIndent := -1
+	blankRun := false
+
+	flushAlign := func() {
+		if len(alignBlock) > 0 {
+			out = append(out, alignColumns(alignBlock, alignIndent)...)
+			alignBlock = nil
+		}
+		alignIndent = -1
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(strings.TrimRight(line, " \t"))
+
+		if inVerbatim != "" {
+			out = append(out, line) // byte-for-byte: verbatim isn't LaTeX-interpreted
+			if trimmed == `\end{`+inVerbatim+`}` {
+				depth--
+				out[len(out)-1] = indentFor(depth) + trimmed
+				inVerbatim = ""
+			}
+			continue
+		}
+
+		if trimmed == "" {
+			if blankRun {
+				continue // collapse runs of blank lines into one
+			}
+			blankRun = true
+			flushAlign()
+			out = append(out, "")
+			continue
+		}
+		blankRun = false
+
+		if endRE.MatchString(trimmed) {
+			flushAlign()
+			depth--
+			if depth < 0 {
+				depth = 0
+			}
+			out = append(out, indentFor(depth)+trimmed)
+			continue
+		}
+
+		if m := beginRE.FindStringSubmatch(trimmed); m != nil {
+			flushAlign()
+			out = append(out, indentFor(depth)+trimmed)
+			depth++
+			if verbatimEnvs[m[1]] {
+				inVerbatim = m[1]
+			} else if alignEnvs[m[1]] {
+				alignIndent = depth
+			}
+			continue
+		}
+
+		if alignIndent == depth && strings.Contains(trimmed, "&") {
+			alignBlock = append(alignBlock, trimmed)
+			continue
+		}
+		flushAlign()
+		out = append(out, indentFor(depth)+trimmed)
+	}
+	flushAlign()
+
+	// Trim leading/trailing blank lines left over from collapsing.
+	for len(out) > 0 && out[0] == "" {
+		out = out[1:]
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func indentFor(depth int) string {
+	if depth <= 0 {
+		return ""
+	}
+	return strings.Repeat(indentUnit, depth)
+}
@@ -0,0 +1,55 @@
+// Package goldentest is a small golden-file test harness, in the style
+// of the golden/vulntest pattern used by several Go-ecosystem projects: a
+// test's expected output lives in a testdata fixture instead of inline in
+// the test source, diffed with go-cmp, and rewritten in place by an
+// -update flag when the expected output intentionally changes.
+package goldentest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Update is true when the test binary was run with -update, in which
+// case Bytes rewrites golden files instead of comparing against them.
+var Update = flag.Bool("update", false, "rewrite golden test fixtures in place")
+
+// T is the subset of *testing.T that Bytes needs, so tests can pass a
+// wrapper when they want to observe a failure without failing themselves.
+type T interface {
+	Helper()
+	Fatalf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// Bytes compares got against the golden file at path after both are run
+// through normalize — a cmp.Transformer, so fields that legitimately vary
+// between runs (timestamps, xref offsets, and the like) never show up in
+// the diff. With -update, it writes got (post-normalize) to path instead
+// of comparing.
+func Bytes(t T, path string, got []byte, normalize func([]byte) []byte) {
+	t.Helper()
+
+	if *Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("goldentest: creating %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, normalize(got), 0o644); err != nil {
+			t.Fatalf("goldentest: writing %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("goldentest: reading %s (run with -update to create it): %v", path, err)
+	}
+
+	transform := cmp.Transformer("normalize", normalize)
+	if diff := cmp.Diff(want, got, transform); diff != "" {
+		t.Errorf("%s does not match golden (-want +got):\n%s", path, diff)
+	}
+}
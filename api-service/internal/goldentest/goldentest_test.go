@@ -0,0 +1,52 @@
+package goldentest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func stripDigits(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c >= '0' && c <= '9' {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func TestBytesComparesAfterNormalizing(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "case.golden")
+	if err := os.WriteFile(path, []byte("value=123"), 0o644); err != nil {
+		t.Fatalf("seeding golden file: %v", err)
+	}
+
+	// Differs only in the digits, which stripDigits zeroes out.
+	Bytes(t, path, []byte("value=456"), stripDigits)
+}
+
+func TestBytesReportsMismatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "case.golden")
+	if err := os.WriteFile(path, []byte("value=abc"), 0o644); err != nil {
+		t.Fatalf("seeding golden file: %v", err)
+	}
+
+	rt := &recordingT{T: t}
+	Bytes(rt, path, []byte("value=xyz"), stripDigits)
+	if !rt.failed {
+		t.Fatal("expected Bytes to report a mismatch")
+	}
+}
+
+// recordingT lets TestBytesReportsMismatch observe a failure from Bytes
+// without failing the outer test itself.
+type recordingT struct {
+	*testing.T
+	failed bool
+}
+
+func (r *recordingT) Errorf(format string, args ...any) { r.failed = true }
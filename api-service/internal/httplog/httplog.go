@@ -0,0 +1,59 @@
+// Package httplog provides structured, per-request JSON logging via zap.
+package httplog
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/auth"
+)
+
+// statusWriter captures the status code a handler wrote, so middleware
+// can log/observe it after the handler returns. It defaults to 200,
+// matching net/http's behavior when a handler never calls WriteHeader.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.status = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Middleware logs one structured line per request — request id, user id
+// (once a Principal has been attached to the context by auth.Middleware),
+// method, path, status, and duration — and, if observe is non-nil, hands
+// the same fields to it so a caller can feed metrics without this package
+// needing to know about Prometheus. newRequestID is called once per
+// request; pass a collision-resistant generator (e.g. the app's uuid()).
+func Middleware(logger *zap.Logger, newRequestID func() string, observe func(route, method string, status int, dur time.Duration)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			reqID := newRequestID()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(sw, r)
+
+			dur := time.Since(start)
+			fields := []zap.Field{
+				zap.String("requestId", reqID),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", sw.status),
+				zap.Duration("duration", dur),
+			}
+			if p, ok := auth.FromContext(r.Context()); ok && p.UserID != "" {
+				fields = append(fields, zap.String("userId", p.UserID))
+			}
+			logger.Info("http_request", fields...)
+
+			if observe != nil {
+				observe(r.URL.Path, r.Method, sw.status, dur)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Package httpx is the single place every HTTP handler encodes a
+// response through, so Content-Type, encoding, and the structured
+// access-log line for that response can't drift handler to handler.
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Logger receives one structured line per Respond call, if set. It is
+// nil until main assigns it during startup (mirroring how the rest of
+// the service wires its zap logger into package-level state), and
+// Respond tolerates a nil Logger so handlers stay testable without it.
+var Logger *zap.Logger
+
+// Respond sets Content-Type and Cache-Control, writes status, and
+// encodes v as the JSON body (unless v is nil, e.g. for a bodyless
+// response). It returns the number of body bytes written, so callers
+// that need it (e.g. tracing middleware) don't have to re-measure it.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v any) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	n := 0
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			if Logger != nil {
+				Logger.Error("httpx: failed to encode response", zap.Error(err), zap.String("path", r.URL.Path))
+			}
+			return 0
+		}
+		written, _ := w.Write(b)
+		n = written
+	}
+
+	if Logger != nil {
+		Logger.Info("http_response",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", status),
+			zap.Int("bytes", n),
+		)
+	}
+	return n
+}
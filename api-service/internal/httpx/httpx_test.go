@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespondEncodesBodyAndSetsHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/version", nil)
+
+	n := Respond(rec, req, 200, map[string]string{"api": "1.0.0"})
+
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	if n != rec.Body.Len() {
+		t.Fatalf("returned byte count %d, want %d", n, rec.Body.Len())
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body["api"] != "1.0.0" {
+		t.Fatalf("unexpected body: %#v", body)
+	}
+}
+
+func TestRespondWithNilBodyWritesNoBytes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/comments/c1", nil)
+
+	n := Respond(rec, req, 204, nil)
+
+	if rec.Code != 204 {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+	if n != 0 || rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body, got %d bytes", rec.Body.Len())
+	}
+}
@@ -0,0 +1,337 @@
+// Package jsonpatch implements RFC 6902 JSON Patch over generic
+// JSON-decoded values (map[string]any, []any, and scalars), plus the
+// RFC 6901 JSON Pointer addressing it relies on. It has no notion of what
+// the document represents — callers round-trip their typed model through
+// encoding/json to get the generic form Apply expects.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Op is one of the six operation kinds defined by RFC 6902.
+type Op string
+
+const (
+	OpAdd     Op = "add"
+	OpRemove  Op = "remove"
+	OpReplace Op = "replace"
+	OpMove    Op = "move"
+	OpCopy    Op = "copy"
+	OpTest    Op = "test"
+)
+
+// Operation is a single step of a Patch.
+type Operation struct {
+	Op    Op     `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Patch is an ordered sequence of Operations, applied atomically: see
+// Apply.
+type Patch []Operation
+
+// Apply runs patch against doc and returns the result. It never mutates
+// doc: every operation runs against a deep copy, and the whole patch is
+// rejected (doc is untouched, err is non-nil) if any single operation —
+// including a failed "test" — would fail.
+func Apply(doc any, patch Patch) (any, error) {
+	working, err := deepCopy(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: copying document: %w", err)
+	}
+	for i, op := range patch {
+		if err := applyOp(&working, op); err != nil {
+			return nil, fmt.Errorf("jsonpatch: op %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return working, nil
+}
+
+func deepCopy(doc any) (any, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func applyOp(root *any, op Operation) error {
+	switch op.Op {
+	case OpAdd:
+		return add(root, op.Path, op.Value)
+	case OpRemove:
+		_, err := remove(root, op.Path)
+		return err
+	case OpReplace:
+		return replace(root, op.Path, op.Value)
+	case OpMove:
+		if op.Path == op.From || strings.HasPrefix(op.Path, op.From+"/") {
+			return fmt.Errorf("cannot move %q into its own subtree %q", op.From, op.Path)
+		}
+		v, err := remove(root, op.From)
+		if err != nil {
+			return err
+		}
+		return add(root, op.Path, v)
+	case OpCopy:
+		v, err := get(*root, op.From)
+		if err != nil {
+			return err
+		}
+		cp, err := deepCopy(v)
+		if err != nil {
+			return err
+		}
+		return add(root, op.Path, cp)
+	case OpTest:
+		v, err := get(*root, op.Path)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(normalize(v), normalize(op.Value)) {
+			return fmt.Errorf("test failed: value at %q does not match", op.Path)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// normalize round-trips a value through JSON so op.Value (decoded once,
+// from the patch body) and values read back out of the document (which
+// went through the same decode) compare equal regardless of how each was
+// originally constructed in Go.
+func normalize(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// slot is a settable location inside the document tree: get reads the
+// current value, set writes a new one back and propagates the change up
+// through every ancestor (needed because replacing a slice element can
+// reallocate the slice, changing its header).
+type slot struct {
+	get func() any
+	set func(any) error
+}
+
+func rootSlot(root *any) slot {
+	return slot{
+		get: func() any { return *root },
+		set: func(v any) error { *root = v; return nil },
+	}
+}
+
+func descend(s slot, tok string) (slot, error) {
+	switch c := s.get().(type) {
+	case map[string]any:
+		if _, ok := c[tok]; !ok {
+			return slot{}, fmt.Errorf("no such member %q", tok)
+		}
+		return slot{
+			get: func() any { return c[tok] },
+			set: func(v any) error { c[tok] = v; return s.set(c) },
+		}, nil
+	case []any:
+		idx, err := arrayIndex(tok, len(c), false)
+		if err != nil {
+			return slot{}, err
+		}
+		return slot{
+			get: func() any { return c[idx] },
+			set: func(v any) error { c[idx] = v; return s.set(c) },
+		}, nil
+	default:
+		return slot{}, fmt.Errorf("cannot descend into %T at %q", c, tok)
+	}
+}
+
+// navigate returns the slot for doc at the location tokens describes,
+// requiring every intermediate member to already exist.
+func navigate(root *any, tokens []string) (slot, error) {
+	s := rootSlot(root)
+	for _, t := range tokens {
+		var err error
+		s, err = descend(s, t)
+		if err != nil {
+			return slot{}, err
+		}
+	}
+	return s, nil
+}
+
+func get(doc any, path string) (any, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	root := doc
+	s, err := navigate(&root, tokens)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(), nil
+}
+
+func add(root *any, path string, value any) error {
+	if path == "" {
+		*root = value
+		return nil
+	}
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return err
+	}
+	parent, err := navigate(root, tokens[:len(tokens)-1])
+	if err != nil {
+		return err
+	}
+	last := tokens[len(tokens)-1]
+	switch c := parent.get().(type) {
+	case map[string]any:
+		c[last] = value
+		return parent.set(c)
+	case []any:
+		idx, err := arrayIndex(last, len(c), true)
+		if err != nil {
+			return err
+		}
+		nc := make([]any, 0, len(c)+1)
+		nc = append(nc, c[:idx]...)
+		nc = append(nc, value)
+		nc = append(nc, c[idx:]...)
+		return parent.set(nc)
+	default:
+		return fmt.Errorf("cannot add a member into %T", c)
+	}
+}
+
+func remove(root *any, path string) (any, error) {
+	if path == "" {
+		return nil, fmt.Errorf("cannot remove the document root")
+	}
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := navigate(root, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch c := parent.get().(type) {
+	case map[string]any:
+		v, ok := c[last]
+		if !ok {
+			return nil, fmt.Errorf("no such member %q", last)
+		}
+		delete(c, last)
+		return v, parent.set(c)
+	case []any:
+		idx, err := arrayIndex(last, len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		v := c[idx]
+		nc := make([]any, 0, len(c)-1)
+		nc = append(nc, c[:idx]...)
+		nc = append(nc, c[idx+1:]...)
+		return v, parent.set(nc)
+	default:
+		return nil, fmt.Errorf("cannot remove a member from %T", c)
+	}
+}
+
+func replace(root *any, path string, value any) error {
+	if path == "" {
+		*root = value
+		return nil
+	}
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return err
+	}
+	parent, err := navigate(root, tokens[:len(tokens)-1])
+	if err != nil {
+		return err
+	}
+	last := tokens[len(tokens)-1]
+	switch c := parent.get().(type) {
+	case map[string]any:
+		if _, ok := c[last]; !ok {
+			return fmt.Errorf("no such member %q", last)
+		}
+		c[last] = value
+		return parent.set(c)
+	case []any:
+		idx, err := arrayIndex(last, len(c), false)
+		if err != nil {
+			return err
+		}
+		c[idx] = value
+		return parent.set(c)
+	default:
+		return fmt.Errorf("cannot replace a member of %T", c)
+	}
+}
+
+// arrayIndex parses a JSON Pointer array token, which is either a
+// non-negative integer or "-" (the element one past the end, valid only
+// when forInsert is true, per RFC 6902 section 4.1).
+func arrayIndex(tok string, length int, forInsert bool) (int, error) {
+	if tok == "-" {
+		if forInsert {
+			return length, nil
+		}
+		return 0, fmt.Errorf("array index \"-\" is not valid here")
+	}
+	idx, err := strconv.Atoi(tok)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	max := length - 1
+	if forInsert {
+		max = length
+	}
+	if idx > max {
+		return 0, fmt.Errorf("array index %d out of range [0,%d]", idx, max)
+	}
+	return idx, nil
+}
+
+// splitPointer decodes an RFC 6901 JSON Pointer into its member/index
+// tokens. The empty pointer "" addresses the whole document.
+func splitPointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("pointer %q must start with \"/\"", ptr)
+	}
+	raw := strings.Split(ptr[1:], "/")
+	tokens := make([]string, len(raw))
+	for i, t := range raw {
+		t = strings.ReplaceAll(t, "~1", "/")
+		t = strings.ReplaceAll(t, "~0", "~")
+		tokens[i] = t
+	}
+	return tokens, nil
+}
@@ -0,0 +1,164 @@
+package jsonpatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func asDoc(t *testing.T, v any) any {
+	t.Helper()
+	cp, err := deepCopy(v)
+	if err != nil {
+		t.Fatalf("deepCopy: %v", err)
+	}
+	return cp
+}
+
+func TestApplyOps(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     any
+		patch   Patch
+		want    any
+		wantErr bool
+	}{
+		{
+			name: "add member",
+			doc:  map[string]any{"title": "Intro"},
+			patch: Patch{
+				{Op: OpAdd, Path: "/author", Value: "Ada"},
+			},
+			want: map[string]any{"title": "Intro", "author": "Ada"},
+		},
+		{
+			name: "add array element by index",
+			doc:  map[string]any{"items": []any{"a", "c"}},
+			patch: Patch{
+				{Op: OpAdd, Path: "/items/1", Value: "b"},
+			},
+			want: map[string]any{"items": []any{"a", "b", "c"}},
+		},
+		{
+			name: "add array element with dash",
+			doc:  map[string]any{"items": []any{"a"}},
+			patch: Patch{
+				{Op: OpAdd, Path: "/items/-", Value: "b"},
+			},
+			want: map[string]any{"items": []any{"a", "b"}},
+		},
+		{
+			name: "remove member",
+			doc:  map[string]any{"title": "Intro", "draft": true},
+			patch: Patch{
+				{Op: OpRemove, Path: "/draft"},
+			},
+			want: map[string]any{"title": "Intro"},
+		},
+		{
+			name: "remove array element",
+			doc:  map[string]any{"items": []any{"a", "b", "c"}},
+			patch: Patch{
+				{Op: OpRemove, Path: "/items/1"},
+			},
+			want: map[string]any{"items": []any{"a", "c"}},
+		},
+		{
+			name: "replace member",
+			doc:  map[string]any{"title": "Intro"},
+			patch: Patch{
+				{Op: OpReplace, Path: "/title", Value: "Introduction"},
+			},
+			want: map[string]any{"title": "Introduction"},
+		},
+		{
+			name:    "replace missing member fails",
+			doc:     map[string]any{"title": "Intro"},
+			patch:   Patch{{Op: OpReplace, Path: "/author", Value: "Ada"}},
+			wantErr: true,
+		},
+		{
+			name: "move member",
+			doc:  map[string]any{"draft": map[string]any{"title": "Intro"}, "final": map[string]any{}},
+			patch: Patch{
+				{Op: OpMove, From: "/draft/title", Path: "/final/title"},
+			},
+			want: map[string]any{"draft": map[string]any{}, "final": map[string]any{"title": "Intro"}},
+		},
+		{
+			name:    "move into own subtree fails",
+			doc:     map[string]any{"a": map[string]any{"b": "x"}},
+			patch:   Patch{{Op: OpMove, From: "/a", Path: "/a/b"}},
+			wantErr: true,
+		},
+		{
+			name: "copy member",
+			doc:  map[string]any{"title": "Intro"},
+			patch: Patch{
+				{Op: OpCopy, From: "/title", Path: "/subtitle"},
+			},
+			want: map[string]any{"title": "Intro", "subtitle": "Intro"},
+		},
+		{
+			name: "test passes then replace applies",
+			doc:  map[string]any{"revision": float64(3)},
+			patch: Patch{
+				{Op: OpTest, Path: "/revision", Value: float64(3)},
+				{Op: OpReplace, Path: "/revision", Value: float64(4)},
+			},
+			want: map[string]any{"revision": float64(4)},
+		},
+		{
+			name:    "failed test rejects whole patch",
+			doc:     map[string]any{"revision": float64(3), "title": "Intro"},
+			patch:   Patch{{Op: OpTest, Path: "/revision", Value: float64(99)}, {Op: OpReplace, Path: "/title", Value: "Changed"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pointer missing leading slash",
+			doc:     map[string]any{"title": "Intro"},
+			patch:   Patch{{Op: OpAdd, Path: "title", Value: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "type mismatch on replace into scalar",
+			doc:     map[string]any{"title": "Intro"},
+			patch:   Patch{{Op: OpReplace, Path: "/title/nested", Value: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "out of range array index",
+			doc:     map[string]any{"items": []any{"a"}},
+			patch:   Patch{{Op: OpReplace, Path: "/items/5", Value: "b"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(asDoc(t, tt.doc), tt.patch)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got result %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, asDoc(t, tt.want)) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLeavesOriginalDocUntouchedOnFailure(t *testing.T) {
+	doc := asDoc(t, map[string]any{"title": "Intro"})
+	_, err := Apply(doc, Patch{{Op: OpReplace, Path: "/missing", Value: "x"}})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !reflect.DeepEqual(doc, asDoc(t, map[string]any{"title": "Intro"})) {
+		t.Errorf("original document was mutated: %#v", doc)
+	}
+}
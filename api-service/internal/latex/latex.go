@@ -0,0 +1,28 @@
+// Package latex provides a small escaper for putting untrusted strings —
+// project titles, author names, filenames — into generated .tex source
+// without them being interpreted as LaTeX markup.
+package latex
+
+import "strings"
+
+// escapeReplacer rewrites the LaTeX-special characters into their escaped
+// forms. strings.Replacer makes a single pass over the input, so the
+// backslashes that the escape sequences introduce are never escaped again.
+var escapeReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`_`, `\_`,
+	`#`, `\#`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`$`, `\$`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+// Escape rewrites s so it renders as literal text when dropped into a .tex
+// document, rather than being interpreted as LaTeX commands or macros.
+func Escape(s string) string {
+	return escapeReplacer.Replace(s)
+}
@@ -0,0 +1,23 @@
+package latex
+
+import "testing"
+
+func TestEscape(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"100% done", `100\% done`},
+		{"a_b#c", `a\_b\#c`},
+		{"$5 & up", `\$5 \& up`},
+		{"{braces}", `\{braces\}`},
+		{"a~b^c", `a\textasciitilde{}b\textasciicircum{}c`},
+		{`back\slash`, `back\textbackslash{}slash`},
+	}
+	for _, c := range cases {
+		if got := Escape(c.in); got != c.want {
+			t.Errorf("Escape(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
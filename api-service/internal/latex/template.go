@@ -0,0 +1,11 @@
+package latex
+
+import "text/template"
+
+// FuncMap returns a text/template.FuncMap exposing Escape as "escape", for
+// templates that render untrusted strings into .tex source, e.g.:
+//
+//	{{.Title | escape}}
+func FuncMap() template.FuncMap {
+	return template.FuncMap{"escape": Escape}
+}
@@ -0,0 +1,179 @@
+// Package logparser turns a raw LaTeX engine log into a structured list
+// of diagnostics, so callers can show "file:line: message" instead of a
+// wall of text.
+package logparser
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity classifies a Diagnostic.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityBadBox  Severity = "badbox"
+)
+
+// Diagnostic is one finding extracted from a log: an error, a warning, or
+// an over/underfull box report. Line and Column are 0 when the log didn't
+// give the parser enough information to locate one.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Message  string   `json:"message"`
+	Rule     string   `json:"rule,omitempty"`
+}
+
+var (
+	engineErrorRE    = regexp.MustCompile(`^! (.+)$`)
+	lineRefRE        = regexp.MustCompile(`^l\.(\d+)\s?`)
+	latexWarningRE   = regexp.MustCompile(`^LaTeX Warning: (.+?)(?: on input line (\d+)\.)?$`)
+	packageWarningRE = regexp.MustCompile(`^Package (\S+) Warning: (.+?)(?: on input line (\d+)\.)?$`)
+	fileLineRE       = regexp.MustCompile(`^File: (\S+) Line: (\d+)`)
+	badBoxRangeRE    = regexp.MustCompile(`^(Overfull|Underfull) \\(hbox|vbox) \((.+?)\) in paragraph at lines (\d+)--\d+$`)
+	badBoxSingleRE   = regexp.MustCompile(`^(Overfull|Underfull) \\(hbox|vbox) \((.+?)\) detected at line (\d+)$`)
+
+	// fileTokenRE recognises the filename-looking token that follows a "("
+	// opened by the engine's file-tracking output (as opposed to a
+	// parenthesis that's just part of ordinary log prose).
+	fileTokenRE = regexp.MustCompile(`^[./]?[\w./-]*\.(tex|sty|cls|cfg|def|ltx|bbl|aux|clo)$`)
+)
+
+// Parse scans a LaTeX engine log and extracts structured diagnostics,
+// tracking the open-file stack via "(filename" / ")" tokens so a
+// diagnostic emitted inside a nested \input resolves to the right source
+// file. Parse is pure: it only reads log and never touches disk.
+func Parse(log []byte) []Diagnostic {
+	p := &parser{}
+	scanner := bufio.NewScanner(strings.NewReader(string(log)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	for i, line := range lines {
+		p.trackFiles(line)
+		currentFile := p.currentFile()
+
+		if m := engineErrorRE.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{Severity: SeverityError, File: currentFile, Message: m[1]}
+			for look := i + 1; look < len(lines) && look <= i+10; look++ {
+				if lm := lineRefRE.FindStringSubmatch(lines[look]); lm != nil {
+					d.Line, _ = strconv.Atoi(lm[1])
+					break
+				}
+			}
+			p.diags = append(p.diags, d)
+			continue
+		}
+
+		if m := latexWarningRE.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{Severity: SeverityWarning, File: currentFile, Message: strings.TrimSuffix(m[1], ".")}
+			if m[2] != "" {
+				d.Line, _ = strconv.Atoi(m[2])
+			}
+			p.diags = append(p.diags, d)
+			continue
+		}
+
+		if m := packageWarningRE.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{Severity: SeverityWarning, File: currentFile, Message: strings.TrimSuffix(m[2], "."), Rule: m[1]}
+			if m[3] != "" {
+				d.Line, _ = strconv.Atoi(m[3])
+			}
+			p.diags = append(p.diags, d)
+			continue
+		}
+
+		if m := badBoxRangeRE.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[4])
+			p.diags = append(p.diags, Diagnostic{
+				Severity: SeverityBadBox, File: currentFile, Line: n,
+				Message: fmt.Sprintf("%s \\%s (%s) in paragraph", m[1], m[2], m[3]),
+			})
+			continue
+		}
+
+		if m := badBoxSingleRE.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[4])
+			p.diags = append(p.diags, Diagnostic{
+				Severity: SeverityBadBox, File: currentFile, Line: n,
+				Message: fmt.Sprintf("%s \\%s (%s)", m[1], m[2], m[3]),
+			})
+			continue
+		}
+
+		// "File: X Line: N" trails a multi-line warning that didn't carry
+		// its own line number; fill in the diagnostic it belongs to.
+		if m := fileLineRE.FindStringSubmatch(line); m != nil && len(p.diags) > 0 {
+			last := &p.diags[len(p.diags)-1]
+			if last.Line == 0 {
+				last.Line, _ = strconv.Atoi(m[2])
+			}
+			continue
+		}
+	}
+	return p.diags
+}
+
+// parser holds the mutable state Parse needs while walking a log:
+// the open-file stack and the diagnostics accumulated so far.
+type parser struct {
+	stack []string // "" marks a parenthesis that wasn't a file open
+	diags []Diagnostic
+}
+
+// currentFile returns the innermost *file* on the stack, skipping over
+// any non-file parenthesis markers above it.
+func (p *parser) currentFile() string {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i] != "" {
+			return p.stack[i]
+		}
+	}
+	return ""
+}
+
+// trackFiles scans line for "(filename" / ")" tokens, pushing/popping the
+// file stack. A "(" not immediately followed by a filename-looking token
+// (e.g. "(see Figure 2)") still pushes a marker so its matching ")"
+// doesn't pop an unrelated file off the stack.
+func (p *parser) trackFiles(line string) {
+	i := 0
+	for i < len(line) {
+		switch line[i] {
+		case '(':
+			j := i + 1
+			for j < len(line) && line[j] != '(' && line[j] != ')' && !isSpace(line[j]) {
+				j++
+			}
+			token := line[i+1 : j]
+			if fileTokenRE.MatchString(token) {
+				p.stack = append(p.stack, token)
+			} else {
+				p.stack = append(p.stack, "")
+			}
+			i = j
+		case ')':
+			if len(p.stack) > 0 {
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+			i++
+		default:
+			i++
+		}
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
@@ -0,0 +1,90 @@
+package logparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return b
+}
+
+func TestParseUndefinedControlSequence(t *testing.T) {
+	diags := Parse(readFixture(t, "undefined_control_sequence.log"))
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	got := diags[0]
+	want := Diagnostic{Severity: SeverityError, File: "./chapter1.tex", Line: 3, Message: "Undefined control sequence."}
+	if got != want {
+		t.Errorf("diagnostic = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	diags := Parse(readFixture(t, "warnings.log"))
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %+v", len(diags), diags)
+	}
+
+	want0 := Diagnostic{Severity: SeverityWarning, File: "./main.tex", Line: 12, Message: "Reference `fig:missing' on page 1 undefined"}
+	if diags[0] != want0 {
+		t.Errorf("diagnostic[0] = %+v, want %+v", diags[0], want0)
+	}
+
+	want1 := Diagnostic{
+		Severity: SeverityWarning, File: "./main.tex", Line: 45, Rule: "hyperref",
+		Message: "Token not allowed in a PDF string (PDFDocEncoding): removing `math shift'",
+	}
+	if diags[1] != want1 {
+		t.Errorf("diagnostic[1] = %+v, want %+v", diags[1], want1)
+	}
+}
+
+func TestParseBadBoxes(t *testing.T) {
+	diags := Parse(readFixture(t, "badboxes.log"))
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %+v", len(diags), diags)
+	}
+
+	for _, d := range diags {
+		if d.Severity != SeverityBadBox {
+			t.Errorf("diagnostic %+v: want severity badbox", d)
+		}
+		if d.File != "./main.tex" {
+			t.Errorf("diagnostic %+v: want file ./main.tex", d)
+		}
+	}
+	if diags[0].Line != 23 {
+		t.Errorf("overfull hbox line = %d, want 23", diags[0].Line)
+	}
+	if diags[1].Line != 45 {
+		t.Errorf("underfull vbox line = %d, want 45", diags[1].Line)
+	}
+}
+
+func TestParseNestedInputResolvesFile(t *testing.T) {
+	log := []byte("(./main.tex\n(./sections/intro.tex\n! Missing $ inserted.\nl.7 some math $x\n)\n)\n")
+	diags := Parse(log)
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	if diags[0].File != "./sections/intro.tex" {
+		t.Errorf("File = %q, want ./sections/intro.tex", diags[0].File)
+	}
+	if diags[0].Line != 7 {
+		t.Errorf("Line = %d, want 7", diags[0].Line)
+	}
+}
+
+func TestParseEmptyLog(t *testing.T) {
+	if diags := Parse(nil); len(diags) != 0 {
+		t.Errorf("Parse(nil) = %+v, want empty", diags)
+	}
+}
@@ -0,0 +1,61 @@
+// Package metrics defines the Prometheus collectors the API, its compile
+// workers, and its WebSocket hub report against, and exposes them at
+// /metrics for scraping.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total HTTP requests handled, labeled by route, method, and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, labeled by route, method, and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	WSConnectionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_connections_active",
+		Help: "Number of currently open WebSocket connections.",
+	})
+
+	WSMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_messages_total",
+		Help: "WebSocket messages handled, labeled by message type and direction (in|out).",
+	}, []string{"type", "direction"})
+
+	CompileJobsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "compile_jobs_total",
+		Help: "Compile jobs completed, labeled by engine and result.",
+	}, []string{"engine", "result"})
+
+	CompileDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "compile_duration_seconds",
+		Help:    "Compile job duration in seconds, labeled by engine.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"engine"})
+
+	CompileQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "compile_queue_depth",
+		Help: "Number of compile jobs currently waiting in the queue.",
+	})
+
+	ProjectFilesBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "project_files_bytes",
+		Help: "Total size of a project's stored working-tree files, in bytes.",
+	}, []string{"project"})
+)
+
+// Handler serves the registry in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,97 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	cellW = 10
+	cellH = 18
+)
+
+// findASCIIBlocks extracts the grid text between "% goat-begin" and
+// "% goat-end" comment markers, stripping the leading "%" (and one space,
+// if present) from each interior line so the ASCII art reads as plain
+// text.
+func findASCIIBlocks(src []byte) [][]byte {
+	var blocks [][]byte
+	lines := strings.Split(string(src), "\n")
+	inBlock := false
+	var cur []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "% goat-begin"):
+			inBlock = true
+			cur = nil
+		case strings.HasPrefix(trimmed, "% goat-end"):
+			if inBlock {
+				blocks = append(blocks, []byte(strings.Join(cur, "\n")))
+			}
+			inBlock = false
+		case inBlock:
+			cur = append(cur, stripCommentPrefix(line))
+		}
+	}
+	return blocks
+}
+
+func stripCommentPrefix(line string) string {
+	trimmed := strings.TrimLeft(line, " \t")
+	rest := strings.TrimPrefix(trimmed, "%")
+	return strings.TrimPrefix(rest, " ")
+}
+
+// renderASCII converts a grid of box-drawing characters into an SVG line
+// drawing. Each character occupies one cellW x cellH cell; recognized
+// characters draw the line segment(s) a goat-style renderer would expect
+// for them. Anything else (including plain text labels) is skipped, since
+// this is a line renderer, not a text layout engine.
+func renderASCII(grid []byte, cfg Config) string {
+	lines := strings.Split(string(grid), "\n")
+	rows := len(lines)
+	cols := 0
+	for _, l := range lines {
+		if len(l) > cols {
+			cols = len(l)
+		}
+	}
+	stroke := cfg.stroke()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		cols*cellW, rows*cellH, cols*cellW, rows*cellH)
+
+	for row, line := range lines {
+		for col, ch := range line {
+			x0, y0 := float64(col*cellW), float64(row*cellH)
+			cx, cy := x0+cellW/2, y0+cellH/2
+			switch ch {
+			case '-':
+				writeLine(&b, x0, cy, x0+cellW, cy, stroke)
+			case '|':
+				writeLine(&b, cx, y0, cx, y0+cellH, stroke)
+			case '+':
+				writeLine(&b, x0, cy, x0+cellW, cy, stroke)
+				writeLine(&b, cx, y0, cx, y0+cellH, stroke)
+			case '/':
+				writeLine(&b, x0, y0+cellH, x0+cellW, y0, stroke)
+			case '\\':
+				writeLine(&b, x0, y0, x0+cellW, y0+cellH, stroke)
+			case '*':
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"/>`, cx, cy, stroke)
+			case 'o':
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="none" stroke="%s"/>`, cx, cy, stroke)
+			}
+		}
+	}
+
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+func writeLine(b *bytes.Buffer, x1, y1, x2, y2 float64, stroke string) {
+	fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="1.5"/>`, x1, y1, x2, y2, stroke)
+}
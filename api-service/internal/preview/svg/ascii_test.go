@@ -0,0 +1,31 @@
+package svg
+
+import "testing"
+
+func TestFindASCIIBlocks(t *testing.T) {
+	src := []byte("\\section{Diagram}\n% goat-begin\n% +--+\n% |  |\n% +--+\n% goat-end\ntext after\n")
+	blocks := findASCIIBlocks(src)
+	if len(blocks) != 1 {
+		t.Fatalf("got %d blocks, want 1", len(blocks))
+	}
+	want := "+--+\n|  |\n+--+"
+	if string(blocks[0]) != want {
+		t.Errorf("block = %q, want %q", blocks[0], want)
+	}
+}
+
+func TestRenderASCIIProducesSVG(t *testing.T) {
+	svg := renderASCII([]byte("+-+\n| |\n+-+"), DefaultConfig)
+	if len(svg) == 0 {
+		t.Fatal("renderASCII returned empty output")
+	}
+	if svg[:4] != "<svg" {
+		t.Errorf("output doesn't start with <svg: %q", svg[:20])
+	}
+}
+
+func TestFindASCIIBlocksNoneWithoutMarkers(t *testing.T) {
+	if blocks := findASCIIBlocks([]byte("\\documentclass{article}\n")); len(blocks) != 0 {
+		t.Errorf("expected no blocks, got %d", len(blocks))
+	}
+}
@@ -0,0 +1,30 @@
+package svg
+
+import "sync"
+
+// Cache holds rendered SVGs keyed by the sha256 content hash of the
+// diagram source that produced them, so re-previewing an unchanged
+// document never re-runs pdflatex/pdf2svg. It's process-local and
+// unbounded: diagram sources are small and a project's diagram count is
+// naturally bounded by the document itself.
+type Cache struct {
+	mu    sync.RWMutex
+	byKey map[string]string
+}
+
+func NewCache() *Cache {
+	return &Cache{byKey: map[string]string{}}
+}
+
+func (c *Cache) Get(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	svg, ok := c.byKey[key]
+	return svg, ok
+}
+
+func (c *Cache) Put(key, svg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byKey[key] = svg
+}
@@ -0,0 +1,101 @@
+// Package svg renders the diagrams embedded in a LaTeX document — TikZ
+// pictures and fenced ASCII-art boxes — to standalone SVG, so the preview
+// pane can show them without waiting on a full PDF recompile.
+package svg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Fragment is one rendered diagram.
+type Fragment struct {
+	Page int    `json:"page"` // 1-indexed position in the list Render returns
+	SVG  string `json:"svg"`
+}
+
+// Config controls stroke color for rendered diagrams, so previews match
+// the editor's light/dark theme. api-service sets the strokes from the
+// SVG_COLOR_LIGHT_SCHEME / SVG_COLOR_DARK_SCHEME environment variables;
+// there are no command-line flags for them.
+type Config struct {
+	LightStroke string
+	DarkStroke  string
+	Dark        bool
+}
+
+// DefaultConfig matches a typical light-theme editor.
+var DefaultConfig = Config{LightStroke: "#202020", DarkStroke: "#e0e0e0"}
+
+func (c Config) stroke() string {
+	if c.Dark && c.DarkStroke != "" {
+		return c.DarkStroke
+	}
+	if c.LightStroke != "" {
+		return c.LightStroke
+	}
+	return "#000000"
+}
+
+// Render finds every ASCII diagram block and tikzpicture environment in
+// src, renders each to SVG (using cache to skip unchanged content), and
+// returns all ASCII diagrams (in source order) followed by all tikzpicture
+// diagrams (in source order); the two kinds are not interleaved by their
+// position in src. compileTikz may be nil, in which case
+// tikzpicture blocks are skipped rather than erroring — a caller without
+// pdflatex/pdf2svg on PATH still gets ASCII diagrams rendered.
+func Render(ctx context.Context, src []byte, cfg Config, cache *Cache, compileTikz TikzCompiler) ([]Fragment, error) {
+	var frags []Fragment
+
+	for _, block := range findASCIIBlocks(src) {
+		svg, err := renderOrCached(cache, block, func() (string, error) {
+			return renderASCII(block, cfg), nil
+		})
+		if err != nil {
+			continue
+		}
+		frags = append(frags, Fragment{Page: len(frags) + 1, SVG: svg})
+	}
+
+	if compileTikz != nil {
+		for _, block := range findTikzBlocks(src) {
+			svg, err := renderOrCached(cache, block, func() (string, error) {
+				return compileTikz(ctx, block)
+			})
+			if err != nil {
+				continue
+			}
+			frags = append(frags, Fragment{Page: len(frags) + 1, SVG: svg})
+		}
+	}
+
+	return frags, nil
+}
+
+// TikzCompiler renders one standalone tikzpicture environment (its full
+// \begin{tikzpicture}...\end{tikzpicture} source) to an SVG string, e.g.
+// by shelling out to pdflatex -shell-escape and pdf2svg.
+type TikzCompiler func(ctx context.Context, tikzSource []byte) (string, error)
+
+func renderOrCached(cache *Cache, content []byte, render func() (string, error)) (string, error) {
+	key := contentHash(content)
+	if cache != nil {
+		if svg, ok := cache.Get(key); ok {
+			return svg, nil
+		}
+	}
+	svg, err := render()
+	if err != nil {
+		return "", err
+	}
+	if cache != nil {
+		cache.Put(key, svg)
+	}
+	return svg, nil
+}
+
+func contentHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
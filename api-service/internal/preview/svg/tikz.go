@@ -0,0 +1,68 @@
+package svg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+)
+
+var tikzEnvRE = regexp.MustCompile(`(?s)\\begin\{tikzpicture\}.*?\\end\{tikzpicture\}`)
+
+// findTikzBlocks returns every standalone \begin{tikzpicture}...\end{tikzpicture}
+// environment in src, in source order.
+func findTikzBlocks(src []byte) [][]byte {
+	return tikzEnvRE.FindAll(src, -1)
+}
+
+// CompileTikz renders one tikzpicture environment to SVG by wrapping it in
+// a minimal standalone document, compiling with `pdflatex -shell-escape`
+// (tikz's externalization library needs shell-escape for some libraries,
+// e.g. `external`), and converting the resulting single-page PDF with
+// pdf2svg. It's a TikzCompiler suitable for passing to Render.
+func CompileTikz(ctx context.Context, tikzSource []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "tikz-preview-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	texPath := filepath.Join(dir, "diagram.tex")
+	pdfPath := filepath.Join(dir, "diagram.pdf")
+	svgPath := filepath.Join(dir, "diagram.svg")
+
+	doc := standaloneWrapper(tikzSource)
+	if err := os.WriteFile(texPath, doc, 0o644); err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, "pdflatex", "-shell-escape", "-interaction=nonstopmode", "-halt-on-error", "diagram.tex")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tikz compile failed: %w: %s", err, stderr.String())
+	}
+
+	cmd = exec.CommandContext(ctx, "pdf2svg", pdfPath, svgPath)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pdf2svg failed: %w", err)
+	}
+
+	svg, err := os.ReadFile(svgPath)
+	if err != nil {
+		return "", err
+	}
+	return string(svg), nil
+}
+
+func standaloneWrapper(tikzSource []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("\\documentclass[tikz,border=1pt]{standalone}\n\\begin{document}\n")
+	b.Write(tikzSource)
+	b.WriteString("\n\\end{document}\n")
+	return b.Bytes()
+}
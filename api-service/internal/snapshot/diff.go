@@ -0,0 +1,227 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileDiff describes how one path changed between two snapshots.
+type FileDiff struct {
+	Path   string `json:"path"`
+	Status string `json:"status"` // "added", "removed", or "modified"
+	Binary bool   `json:"binary,omitempty"`
+	Patch  string `json:"patch,omitempty"`
+}
+
+// Diff compares two snapshots and returns a unified diff for every text
+// file whose content changed; binary files are reported by name only.
+func (s *Store) Diff(ctx context.Context, projectID, fromID, toID string) ([]FileDiff, error) {
+	from, err := s.Get(ctx, projectID, fromID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: loading %s: %w", fromID, err)
+	}
+	to, err := s.Get(ctx, projectID, toID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: loading %s: %w", toID, err)
+	}
+
+	paths := map[string]bool{}
+	for p := range from.Files {
+		paths[p] = true
+	}
+	for p := range to.Files {
+		paths[p] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var diffs []FileDiff
+	for _, path := range sorted {
+		fromRef, hadBefore := from.Files[path]
+		toRef, hasAfter := to.Files[path]
+		if hadBefore && hasAfter && fromRef.SHA256 == toRef.SHA256 {
+			continue
+		}
+
+		fd := FileDiff{Path: path}
+		switch {
+		case !hadBefore:
+			fd.Status = "added"
+		case !hasAfter:
+			fd.Status = "removed"
+		default:
+			fd.Status = "modified"
+		}
+
+		var beforeContent, afterContent []byte
+		if hadBefore {
+			if beforeContent, err = s.readObject(ctx, objectKey(projectID, fromRef.SHA256)); err != nil {
+				return nil, err
+			}
+		}
+		if hasAfter {
+			if afterContent, err = s.readObject(ctx, objectKey(projectID, toRef.SHA256)); err != nil {
+				return nil, err
+			}
+		}
+
+		if looksBinary(beforeContent) || looksBinary(afterContent) {
+			fd.Binary = true
+		} else {
+			fd.Patch = unifiedDiff(path, splitLines(string(beforeContent)), splitLines(string(afterContent)))
+		}
+		diffs = append(diffs, fd)
+	}
+	return diffs, nil
+}
+
+func looksBinary(b []byte) bool {
+	return bytes.IndexByte(b, 0) != -1
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type diffOp struct {
+	kind byte // ' ' equal, '-' only in "from", '+' only in "to"
+	text string
+}
+
+// unifiedDiff renders a `diff -u`-style patch for path from an LCS-based
+// line diff. The DP table is O(len(from)*len(to)) which is fine for
+// document-sized text files; it is not meant for huge generated sources.
+func unifiedDiff(path string, from, to []string) string {
+	ops := lineDiff(from, to)
+	hunks := groupHunks(ops, 3)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n", path)
+	fmt.Fprintf(&sb, "+++ b/%s\n", path)
+	for _, h := range hunks {
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", h.fromStart, h.fromLen, h.toStart, h.toLen)
+		for _, op := range ops[h.lo : h.hi+1] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+func lineDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] >= dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+type hunk struct {
+	lo, hi              int // inclusive index range into ops
+	fromStart, fromLen  int
+	toStart, toLen      int
+}
+
+// groupHunks clusters changed ops (plus `context` lines of surrounding
+// equal lines on each side) into unified-diff hunks, merging clusters
+// that are close enough together that their context would overlap.
+func groupHunks(ops []diffOp, context int) []hunk {
+	fromPos := make([]int, len(ops)+1)
+	toPos := make([]int, len(ops)+1)
+	for k, op := range ops {
+		fromPos[k+1], toPos[k+1] = fromPos[k], toPos[k]
+		switch op.kind {
+		case ' ':
+			fromPos[k+1]++
+			toPos[k+1]++
+		case '-':
+			fromPos[k+1]++
+		case '+':
+			toPos[k+1]++
+		}
+	}
+
+	var changed []int
+	for k, op := range ops {
+		if op.kind != ' ' {
+			changed = append(changed, k)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var hunks []hunk
+	i := 0
+	for i < len(changed) {
+		lo, hi := changed[i], changed[i]
+		j := i
+		for j+1 < len(changed) && changed[j+1]-hi-1 <= 2*context {
+			hi = changed[j+1]
+			j++
+		}
+		lo -= context
+		if lo < 0 {
+			lo = 0
+		}
+		hi += context
+		if hi > len(ops)-1 {
+			hi = len(ops) - 1
+		}
+		hunks = append(hunks, hunk{
+			lo: lo, hi: hi,
+			fromStart: fromPos[lo] + 1, fromLen: fromPos[hi+1] - fromPos[lo],
+			toStart: toPos[lo] + 1, toLen: toPos[hi+1] - toPos[lo],
+		})
+		i = j + 1
+	}
+	return hunks
+}
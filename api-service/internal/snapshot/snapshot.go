@@ -0,0 +1,300 @@
+// Package snapshot implements git-like, content-addressed version
+// history for a project's working tree: every snapshot is a manifest
+// mapping path -> blob SHA-256, with blobs deduplicated across the
+// project's whole history.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/storage"
+)
+
+// FileRef is one entry in a Manifest: the content hash and size of a
+// single file as of that snapshot.
+type FileRef struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Manifest is one immutable snapshot of a project's working tree.
+type Manifest struct {
+	ID        string             `json:"id"`
+	ParentID  string             `json:"parentId,omitempty"`
+	Message   string             `json:"message,omitempty"`
+	Author    string             `json:"author,omitempty"`
+	Revision  string             `json:"revision,omitempty"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Files     map[string]FileRef `json:"files"`
+}
+
+// Options carries the caller-supplied metadata for a new snapshot.
+type Options struct {
+	ParentID string
+	Message  string
+	Author   string
+	Revision string
+}
+
+// Store implements snapshot creation, retrieval, and GC on top of a
+// project's storage.FS backend. Blobs live under
+// "<projectID>/.snapshots/objects/<sha256>" and manifests under
+// "<projectID>/.snapshots/manifests/<snapshotID>.json", so they ride
+// along with the rest of the project's files regardless of backend.
+type Store struct {
+	fs storage.FS
+}
+
+func NewStore(fs storage.FS) *Store {
+	return &Store{fs: fs}
+}
+
+func objectsPrefix(projectID string) string   { return projectID + "/.snapshots/objects/" }
+func manifestsPrefix(projectID string) string { return projectID + "/.snapshots/manifests/" }
+
+func objectKey(projectID, sha string) string {
+	return objectsPrefix(projectID) + sha
+}
+
+func manifestKey(projectID, id string) string {
+	return manifestsPrefix(projectID) + id + ".json"
+}
+
+// isReservedPath reports whether rel (a path relative to the project
+// root) belongs to snapshot bookkeeping, compile scratch space, or the
+// compiled output, and so should never itself be snapshotted or restored.
+func isReservedPath(rel string) bool {
+	return rel == "" ||
+		rel == ".snapshots" || strings.HasPrefix(rel, ".snapshots/") ||
+		rel == "compile" || strings.HasPrefix(rel, "compile/") ||
+		rel == "output.pdf"
+}
+
+// Create hashes every file currently in projectID's working tree,
+// writes any blob not already stored, and persists a new manifest
+// recording the result. Identical content across snapshots of the same
+// project is stored only once; each project keeps its own blob store.
+func (s *Store) Create(ctx context.Context, projectID string, opts Options) (*Manifest, error) {
+	prefix := projectID + "/"
+	objs, err := s.fs.ReadDir(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: listing working tree: %w", err)
+	}
+
+	existing, err := s.fs.ReadDir(ctx, objectsPrefix(projectID))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: listing objects: %w", err)
+	}
+	haveBlob := make(map[string]bool, len(existing))
+	for _, o := range existing {
+		haveBlob[strings.TrimPrefix(o.Name, objectsPrefix(projectID))] = true
+	}
+
+	files := map[string]FileRef{}
+	for _, o := range objs {
+		if o.IsDir {
+			continue
+		}
+		rel := strings.TrimPrefix(o.Name, prefix)
+		if isReservedPath(rel) {
+			continue
+		}
+		content, err := s.readObject(ctx, o.Name)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: reading %s: %w", rel, err)
+		}
+		sha := hashBytes(content)
+		if !haveBlob[sha] {
+			if err := s.fs.WriteFile(ctx, objectKey(projectID, sha), bytes.NewReader(content), int64(len(content))); err != nil {
+				return nil, fmt.Errorf("snapshot: writing blob %s: %w", sha, err)
+			}
+			haveBlob[sha] = true
+		}
+		files[rel] = FileRef{SHA256: sha, Size: int64(len(content))}
+	}
+
+	m := &Manifest{
+		ID:        newSnapshotID(),
+		ParentID:  opts.ParentID,
+		Message:   opts.Message,
+		Author:    opts.Author,
+		Revision:  opts.Revision,
+		CreatedAt: time.Now().UTC(),
+		Files:     files,
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fs.WriteFile(ctx, manifestKey(projectID, m.ID), bytes.NewReader(b), int64(len(b))); err != nil {
+		return nil, fmt.Errorf("snapshot: writing manifest: %w", err)
+	}
+	return m, nil
+}
+
+// List returns every manifest recorded for projectID, newest first.
+func (s *Store) List(ctx context.Context, projectID string) ([]*Manifest, error) {
+	objs, err := s.fs.ReadDir(ctx, manifestsPrefix(projectID))
+	if err != nil {
+		return nil, err
+	}
+	manifests := make([]*Manifest, 0, len(objs))
+	for _, o := range objs {
+		if o.IsDir {
+			continue
+		}
+		b, err := s.readObject(ctx, o.Name)
+		if err != nil {
+			continue
+		}
+		var m Manifest
+		if json.Unmarshal(b, &m) != nil {
+			continue
+		}
+		manifests = append(manifests, &m)
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].CreatedAt.After(manifests[j].CreatedAt) })
+	return manifests, nil
+}
+
+// Get loads a single snapshot's manifest.
+func (s *Store) Get(ctx context.Context, projectID, snapshotID string) (*Manifest, error) {
+	b, err := s.readObject(ctx, manifestKey(projectID, snapshotID))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("snapshot: corrupt manifest %s: %w", snapshotID, err)
+	}
+	return &m, nil
+}
+
+// FileAt returns the content of path as it existed in snapshotID.
+func (s *Store) FileAt(ctx context.Context, projectID, snapshotID, path string) ([]byte, error) {
+	m, err := s.Get(ctx, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	ref, ok := m.Files[path]
+	if !ok {
+		return nil, fmt.Errorf("snapshot: %s has no file %q", snapshotID, path)
+	}
+	return s.readObject(ctx, objectKey(projectID, ref.SHA256))
+}
+
+// Restore materializes snapshotID back into projectID's working tree —
+// overwriting files the snapshot recorded and deleting ones it doesn't —
+// then records that as a new snapshot of its own, so restoring is itself
+// undoable.
+func (s *Store) Restore(ctx context.Context, projectID, snapshotID string, opts Options) (*Manifest, error) {
+	target, err := s.Get(ctx, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := projectID + "/"
+	objs, err := s.fs.ReadDir(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: listing working tree: %w", err)
+	}
+	for _, o := range objs {
+		if o.IsDir {
+			continue
+		}
+		rel := strings.TrimPrefix(o.Name, prefix)
+		if isReservedPath(rel) {
+			continue
+		}
+		if _, ok := target.Files[rel]; !ok {
+			if err := s.fs.Remove(ctx, o.Name); err != nil {
+				return nil, fmt.Errorf("snapshot: removing %s: %w", rel, err)
+			}
+		}
+	}
+
+	for path, ref := range target.Files {
+		content, err := s.readObject(ctx, objectKey(projectID, ref.SHA256))
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: reading blob for %s: %w", path, err)
+		}
+		if err := s.fs.WriteFile(ctx, prefix+path, bytes.NewReader(content), int64(len(content))); err != nil {
+			return nil, fmt.Errorf("snapshot: restoring %s: %w", path, err)
+		}
+	}
+
+	if opts.Message == "" {
+		opts.Message = "restore to " + snapshotID
+	}
+	if opts.ParentID == "" {
+		opts.ParentID = snapshotID
+	}
+	return s.Create(ctx, projectID, opts)
+}
+
+// GC removes every blob under projectID's object store that isn't
+// referenced by any manifest, and returns how many it removed. Safe to
+// run concurrently with Create: a blob written mid-GC is always
+// reachable from the manifest that triggered its write, so it is never
+// swept (GC only ever deletes blobs absent from every manifest it read).
+func (s *Store) GC(ctx context.Context, projectID string) (int, error) {
+	manifests, err := s.List(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+	referenced := map[string]bool{}
+	for _, m := range manifests {
+		for _, ref := range m.Files {
+			referenced[ref.SHA256] = true
+		}
+	}
+
+	blobs, err := s.fs.ReadDir(ctx, objectsPrefix(projectID))
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, b := range blobs {
+		if b.IsDir {
+			continue
+		}
+		sha := strings.TrimPrefix(b.Name, objectsPrefix(projectID))
+		if referenced[sha] {
+			continue
+		}
+		if err := s.fs.Remove(ctx, b.Name); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *Store) readObject(ctx context.Context, key string) ([]byte, error) {
+	rc, err := s.fs.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func newSnapshotID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
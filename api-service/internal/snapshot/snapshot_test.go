@@ -0,0 +1,233 @@
+package snapshot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/storage"
+)
+
+func writeProjectFile(t *testing.T, fs storage.FS, path, content string) {
+	t.Helper()
+	if err := fs.WriteFile(context.Background(), path, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("WriteFile(%s): %v", path, err)
+	}
+}
+
+func TestCreateRecordsFilesAndDedupsBlobs(t *testing.T) {
+	ctx := context.Background()
+	fs := storage.NewMemStorage()
+	writeProjectFile(t, fs, "p1/main.tex", "\\documentclass{article}\n")
+	writeProjectFile(t, fs, "p1/fig.png", "\\documentclass{article}\n") // identical content, different path
+
+	store := NewStore(fs)
+	m, err := store.Create(ctx, "p1", Options{Message: "first"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(m.Files) != 2 {
+		t.Fatalf("got %d files, want 2: %#v", len(m.Files), m.Files)
+	}
+	if m.Files["main.tex"].SHA256 != m.Files["fig.png"].SHA256 {
+		t.Fatalf("identical content should hash the same: %#v", m.Files)
+	}
+
+	blobs, err := fs.ReadDir(ctx, objectsPrefix("p1"))
+	if err != nil {
+		t.Fatalf("ReadDir objects: %v", err)
+	}
+	if len(blobs) != 1 {
+		t.Fatalf("got %d blobs, want 1 (deduped): %#v", len(blobs), blobs)
+	}
+}
+
+func TestCreateIgnoresReservedPaths(t *testing.T) {
+	ctx := context.Background()
+	fs := storage.NewMemStorage()
+	writeProjectFile(t, fs, "p1/main.tex", "hello")
+	writeProjectFile(t, fs, "p1/output.pdf", "pdf bytes")
+	writeProjectFile(t, fs, "p1/compile/main.log", "log")
+
+	store := NewStore(fs)
+	m, err := store.Create(ctx, "p1", Options{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(m.Files) != 1 {
+		t.Fatalf("got %d files, want 1: %#v", len(m.Files), m.Files)
+	}
+	if _, ok := m.Files["main.tex"]; !ok {
+		t.Fatalf("expected main.tex to be tracked: %#v", m.Files)
+	}
+}
+
+func TestListReturnsNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	fs := storage.NewMemStorage()
+	writeProjectFile(t, fs, "p1/main.tex", "v1")
+	store := NewStore(fs)
+
+	first, err := store.Create(ctx, "p1", Options{Message: "v1"})
+	if err != nil {
+		t.Fatalf("Create v1: %v", err)
+	}
+	writeProjectFile(t, fs, "p1/main.tex", "v2")
+	second, err := store.Create(ctx, "p1", Options{Message: "v2", ParentID: first.ID})
+	if err != nil {
+		t.Fatalf("Create v2: %v", err)
+	}
+
+	manifests, err := store.List(ctx, "p1")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(manifests) != 2 || manifests[0].ID != second.ID || manifests[1].ID != first.ID {
+		t.Fatalf("unexpected order: %#v", manifests)
+	}
+}
+
+func TestFileAtReturnsContentAsOfSnapshot(t *testing.T) {
+	ctx := context.Background()
+	fs := storage.NewMemStorage()
+	writeProjectFile(t, fs, "p1/main.tex", "v1")
+	store := NewStore(fs)
+
+	m, err := store.Create(ctx, "p1", Options{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	b, err := store.FileAt(ctx, "p1", m.ID, "main.tex")
+	if err != nil {
+		t.Fatalf("FileAt: %v", err)
+	}
+	if string(b) != "v1" {
+		t.Fatalf("got %q, want %q", b, "v1")
+	}
+
+	if _, err := store.FileAt(ctx, "p1", m.ID, "missing.tex"); err == nil {
+		t.Fatal("expected error for a path not in the snapshot")
+	}
+}
+
+func TestRestoreRewritesWorkingTreeAndIsUndoable(t *testing.T) {
+	ctx := context.Background()
+	fs := storage.NewMemStorage()
+	writeProjectFile(t, fs, "p1/main.tex", "v1")
+	writeProjectFile(t, fs, "p1/extra.tex", "will be removed")
+	store := NewStore(fs)
+
+	v1, err := store.Create(ctx, "p1", Options{})
+	if err != nil {
+		t.Fatalf("Create v1: %v", err)
+	}
+
+	writeProjectFile(t, fs, "p1/main.tex", "v2")
+	if err := fs.Remove(ctx, "p1/extra.tex"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := store.Create(ctx, "p1", Options{ParentID: v1.ID}); err != nil {
+		t.Fatalf("Create v2: %v", err)
+	}
+
+	restored, err := store.Restore(ctx, "p1", v1.ID, Options{})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.ParentID != v1.ID {
+		t.Fatalf("restore manifest should chain off the target snapshot, got parent %q", restored.ParentID)
+	}
+
+	rc, err := fs.Open(ctx, "p1/main.tex")
+	if err != nil {
+		t.Fatalf("Open restored main.tex: %v", err)
+	}
+	rc.Close()
+	if _, err := fs.Open(ctx, "p1/extra.tex"); err != nil {
+		t.Fatalf("expected extra.tex to be restored, got: %v", err)
+	}
+
+	b, err := store.FileAt(ctx, "p1", restored.ID, "main.tex")
+	if err != nil || string(b) != "v1" {
+		t.Fatalf("restored content = %q, err %v, want %q", b, err, "v1")
+	}
+}
+
+func TestGCRemovesOnlyUnreferencedBlobs(t *testing.T) {
+	ctx := context.Background()
+	fs := storage.NewMemStorage()
+	writeProjectFile(t, fs, "p1/main.tex", "v1")
+	store := NewStore(fs)
+
+	if _, err := store.Create(ctx, "p1", Options{}); err != nil {
+		t.Fatalf("Create v1: %v", err)
+	}
+	writeProjectFile(t, fs, "p1/main.tex", "v2")
+	if _, err := store.Create(ctx, "p1", Options{}); err != nil {
+		t.Fatalf("Create v2: %v", err)
+	}
+
+	// A stray blob no manifest references.
+	if err := fs.WriteFile(ctx, objectKey("p1", "deadbeef"), strings.NewReader("orphan"), int64(len("orphan"))); err != nil {
+		t.Fatalf("WriteFile orphan blob: %v", err)
+	}
+
+	removed, err := store.GC(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("removed = %d, want 1", removed)
+	}
+
+	blobs, err := fs.ReadDir(ctx, objectsPrefix("p1"))
+	if err != nil {
+		t.Fatalf("ReadDir objects: %v", err)
+	}
+	if len(blobs) != 2 {
+		t.Fatalf("got %d blobs after GC, want 2 (v1 and v2 content): %#v", len(blobs), blobs)
+	}
+}
+
+func TestDiffReportsAddedRemovedAndModified(t *testing.T) {
+	ctx := context.Background()
+	fs := storage.NewMemStorage()
+	writeProjectFile(t, fs, "p1/main.tex", "line one\nline two\n")
+	writeProjectFile(t, fs, "p1/removed.tex", "bye")
+	store := NewStore(fs)
+
+	from, err := store.Create(ctx, "p1", Options{})
+	if err != nil {
+		t.Fatalf("Create from: %v", err)
+	}
+
+	if err := fs.Remove(ctx, "p1/removed.tex"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	writeProjectFile(t, fs, "p1/main.tex", "line one\nline TWO\n")
+	writeProjectFile(t, fs, "p1/added.tex", "new")
+	to, err := store.Create(ctx, "p1", Options{ParentID: from.ID})
+	if err != nil {
+		t.Fatalf("Create to: %v", err)
+	}
+
+	diffs, err := store.Diff(ctx, "p1", from.ID, to.ID)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+
+	byPath := map[string]FileDiff{}
+	for _, d := range diffs {
+		byPath[d.Path] = d
+	}
+	if byPath["added.tex"].Status != "added" {
+		t.Errorf("added.tex status = %q, want added", byPath["added.tex"].Status)
+	}
+	if byPath["removed.tex"].Status != "removed" {
+		t.Errorf("removed.tex status = %q, want removed", byPath["removed.tex"].Status)
+	}
+	if byPath["main.tex"].Status != "modified" || byPath["main.tex"].Patch == "" {
+		t.Errorf("main.tex diff = %#v, want a modified entry with a patch", byPath["main.tex"])
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GCSConfig carries the env-driven settings used to reach a Google Cloud
+// Storage bucket: GCS_BUCKET and GCS_CREDENTIALS_FILE. An empty
+// CredentialsFile falls back to application-default credentials (the
+// usual case when running on GCE/GKE).
+type GCSConfig struct {
+	Bucket          string
+	CredentialsFile string
+}
+
+// GCSStorage stores files in a single bucket, keyed directly by the FS
+// name (e.g. "projects/<id>/main.tex").
+type GCSStorage struct {
+	bucket *gcs.BucketHandle
+	name   string
+}
+
+func NewGCSStorage(cfg GCSConfig) (*GCSStorage, error) {
+	var opts []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+	client, err := gcs.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &GCSStorage{bucket: client.Bucket(cfg.Bucket), name: cfg.Bucket}, nil
+}
+
+func (g *GCSStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return g.bucket.Object(name).NewReader(ctx)
+}
+
+func (g *GCSStorage) Stat(ctx context.Context, name string) (FileInfo, error) {
+	attrs, err := g.bucket.Object(name).Attrs(ctx)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{Name: attrs.Name, Size: attrs.Size, ModTime: attrs.Updated}, nil
+}
+
+func (g *GCSStorage) WriteFile(ctx context.Context, name string, r io.Reader, size int64) error {
+	w := g.bucket.Object(name).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (g *GCSStorage) Remove(ctx context.Context, name string) error {
+	err := g.bucket.Object(name).Delete(ctx)
+	if err == gcs.ErrObjectNotExist {
+		return nil
+	}
+	return err
+}
+
+func (g *GCSStorage) RemoveAll(ctx context.Context, prefix string) error {
+	it := g.bucket.Objects(ctx, &gcs.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := g.bucket.Object(attrs.Name).Delete(ctx); err != nil && err != gcs.ErrObjectNotExist {
+			return err
+		}
+	}
+}
+
+func (g *GCSStorage) ReadDir(ctx context.Context, prefix string) ([]FileInfo, error) {
+	var out []FileInfo
+	it := g.bucket.Objects(ctx, &gcs.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, FileInfo{
+			Name:    attrs.Name,
+			Size:    attrs.Size,
+			ModTime: attrs.Updated,
+			IsDir:   strings.HasSuffix(attrs.Name, "/"),
+		})
+	}
+}
+
+// PresignedGetURL has no meaning without a service-account key to sign
+// with (application-default credentials can't); callers fall back to
+// Open.
+func (g *GCSStorage) PresignedGetURL(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	return "", ErrNotSupported
+}
+
+// Ping confirms the configured bucket exists and is reachable.
+func (g *GCSStorage) Ping(ctx context.Context) error {
+	_, err := g.bucket.Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("storage: gcs bucket %q unreachable: %w", g.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalFS stores files as plain files under root, preserving the
+// behavior the service had before FS existed.
+type LocalFS struct {
+	root string
+}
+
+func NewLocalFS(root string) *LocalFS {
+	return &LocalFS{root: root}
+}
+
+func (l *LocalFS) path(name string) string {
+	return filepath.Join(l.root, filepath.FromSlash(name))
+}
+
+func (l *LocalFS) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(l.path(name))
+}
+
+func (l *LocalFS) Stat(ctx context.Context, name string) (FileInfo, error) {
+	info, err := os.Stat(l.path(name))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{Name: name, Size: info.Size(), ModTime: info.ModTime(), IsDir: info.IsDir()}, nil
+}
+
+func (l *LocalFS) WriteFile(ctx context.Context, name string, r io.Reader, size int64) error {
+	dst := l.path(name)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (l *LocalFS) Remove(ctx context.Context, name string) error {
+	err := os.Remove(l.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (l *LocalFS) RemoveAll(ctx context.Context, prefix string) error {
+	err := os.RemoveAll(l.path(prefix))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (l *LocalFS) ReadDir(ctx context.Context, prefix string) ([]FileInfo, error) {
+	root := l.path(prefix)
+	var out []FileInfo
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || p == root {
+			return nil
+		}
+		rel, relErr := filepath.Rel(l.root, p)
+		if relErr != nil {
+			return relErr
+		}
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			return infoErr
+		}
+		out = append(out, FileInfo{
+			Name:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   d.IsDir(),
+		})
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return out, err
+}
+
+// PresignedGetURL has no meaning for local disk; callers fall back to Open.
+func (l *LocalFS) PresignedGetURL(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	return "", ErrNotSupported
+}
+
+// Ping confirms root exists and is a directory.
+func (l *LocalFS) Ping(ctx context.Context) error {
+	info, err := os.Stat(l.root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("storage: local root %q is not a directory", l.root)
+	}
+	return nil
+}
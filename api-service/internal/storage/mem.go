@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemStorage is a process-local, mutex-guarded FS backed by a plain map.
+// It exists for tests that want a real FS (so handler code doesn't need
+// a LocalFS tempdir) without any disk or network access.
+type MemStorage struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	modTime map[string]time.Time
+}
+
+func NewMemStorage() *MemStorage {
+	return &MemStorage{
+		files:   map[string][]byte{},
+		modTime: map[string]time.Time{},
+	}
+}
+
+func (m *MemStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.files[name]
+	if !ok {
+		return nil, fmt.Errorf("storage: no such file %q", name)
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *MemStorage) Stat(ctx context.Context, name string) (FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.files[name]
+	if !ok {
+		return FileInfo{}, fmt.Errorf("storage: no such file %q", name)
+	}
+	return FileInfo{Name: name, Size: int64(len(b)), ModTime: m.modTime[name]}, nil
+}
+
+func (m *MemStorage) WriteFile(ctx context.Context, name string, r io.Reader, size int64) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = b
+	m.modTime[name] = time.Now()
+	return nil
+}
+
+func (m *MemStorage) Remove(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, name)
+	delete(m.modTime, name)
+	return nil
+}
+
+func (m *MemStorage) RemoveAll(ctx context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.files {
+		if strings.HasPrefix(name, prefix) {
+			delete(m.files, name)
+			delete(m.modTime, name)
+		}
+	}
+	return nil
+}
+
+func (m *MemStorage) ReadDir(ctx context.Context, prefix string) ([]FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []FileInfo
+	for name, b := range m.files {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, FileInfo{
+				Name:    name,
+				Size:    int64(len(b)),
+				ModTime: m.modTime[name],
+			})
+		}
+	}
+	return out, nil
+}
+
+// PresignedGetURL has no meaning for an in-memory store; callers fall
+// back to Open.
+func (m *MemStorage) PresignedGetURL(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	return "", ErrNotSupported
+}
+
+// Ping always succeeds: there's no external dependency to be unreachable.
+func (m *MemStorage) Ping(ctx context.Context) error {
+	return nil
+}
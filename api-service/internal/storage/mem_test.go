@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMemStorageWriteOpenReadDirRemoveAll(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemStorage()
+
+	if err := s.WriteFile(ctx, "projects/p1/main.tex", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := s.WriteFile(ctx, "projects/p1/fig.png", strings.NewReader("binary"), 6); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	rc, err := s.Open(ctx, "projects/p1/main.tex")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil || string(b) != "hello" {
+		t.Fatalf("Open content = %q, err %v, want %q", b, err, "hello")
+	}
+
+	files, err := s.ReadDir(ctx, "projects/p1/")
+	if err != nil || len(files) != 2 {
+		t.Fatalf("ReadDir = %#v, err %v, want 2 files", files, err)
+	}
+
+	if err := s.RemoveAll(ctx, "projects/p1/"); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if files, _ := s.ReadDir(ctx, "projects/p1/"); len(files) != 0 {
+		t.Fatalf("expected no files after RemoveAll, got %#v", files)
+	}
+	if _, err := s.Open(ctx, "projects/p1/main.tex"); err == nil {
+		t.Fatal("expected Open after RemoveAll to fail")
+	}
+}
+
+func TestMemStoragePing(t *testing.T) {
+	if err := NewMemStorage().Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
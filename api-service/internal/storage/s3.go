@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config carries the env-driven settings used to reach an S3/MinIO
+// endpoint: S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY,
+// S3_USE_SSL.
+type S3Config struct {
+	Endpoint  string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3Storage stores files in a single bucket, keyed directly by the FS
+// name (e.g. "projects/<id>/main.tex").
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (s *S3Storage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+}
+
+func (s *S3Storage) Stat(ctx context.Context, name string) (FileInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{Name: name, Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (s *S3Storage) WriteFile(ctx context.Context, name string, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, s.bucket, name, r, size, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *S3Storage) Remove(ctx context.Context, name string) error {
+	return s.client.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Storage) RemoveAll(ctx context.Context, prefix string) error {
+	objectsCh := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	for obj := range objectsCh {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := s.client.RemoveObject(ctx, s.bucket, obj.Key, minio.RemoveObjectOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Storage) ReadDir(ctx context.Context, prefix string) ([]FileInfo, error) {
+	var out []FileInfo
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		out = append(out, FileInfo{
+			Name:    obj.Key,
+			Size:    obj.Size,
+			ModTime: obj.LastModified,
+			IsDir:   strings.HasSuffix(obj.Key, "/"),
+		})
+	}
+	return out, nil
+}
+
+func (s *S3Storage) PresignedGetURL(ctx context.Context, name string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, name, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Ping confirms the configured bucket exists and is reachable.
+func (s *S3Storage) Ping(ctx context.Context) error {
+	ok, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("storage: bucket %q does not exist", s.bucket)
+	}
+	return nil
+}
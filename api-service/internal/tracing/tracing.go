@@ -0,0 +1,105 @@
+// Package tracing wires OpenTelemetry distributed tracing into the API:
+// an HTTP middleware that continues an incoming traceparent (or starts a
+// new trace), a span per request named after its route, and an OTLP
+// exporter when OTEL_EXPORTER_OTLP_ENDPOINT is configured. When it isn't,
+// Init installs a no-op provider so Middleware is always safe to wire in.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// Init configures the global TracerProvider and text-map propagator. If
+// OTEL_EXPORTER_OTLP_ENDPOINT is unset, it leaves the default (no-op)
+// provider in place and returns a no-op shutdown, so Middleware can be
+// wired in unconditionally without a collector running locally. The
+// returned shutdown flushes and closes the exporter; callers should defer
+// it.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// responseRecorder captures the status and byte count a handler wrote, so
+// Middleware can record them on the span after the handler returns.
+// Modeled on httplog.statusWriter, extended to also track bytes written.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	rr.status = code
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+// Middleware starts a span per request, continuing any traceparent header
+// on the incoming request, named after routeName(r). It records the
+// response status code and body size as span attributes, and, for
+// /api/compile, the handler's wall-clock duration as compile.duration_ms.
+func Middleware(routeName func(*http.Request) string) func(http.Handler) http.Handler {
+	tracer := otel.Tracer("github.com/vvvigya/latex-editor/api-service")
+	propagator := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, routeName(r))
+			defer span.End()
+
+			rr := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rr, r.WithContext(ctx))
+			dur := time.Since(start)
+
+			span.SetAttributes(
+				attribute.Int("http.status_code", rr.status),
+				attribute.Int("http.response_content_length", rr.bytes),
+			)
+			if r.URL.Path == "/api/compile" {
+				span.SetAttributes(attribute.Int64("compile.duration_ms", dur.Milliseconds()))
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddlewareRecordsSpanWithStatusAndBytes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	mw := Middleware(func(r *http.Request) string { return r.URL.Path })
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/version", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "/api/version" {
+		t.Fatalf("span name = %q, want /api/version", span.Name)
+	}
+
+	attrs := map[string]int64{}
+	for _, kv := range span.Attributes {
+		attrs[string(kv.Key)] = kv.Value.AsInt64()
+	}
+	if attrs["http.status_code"] != http.StatusCreated {
+		t.Fatalf("http.status_code = %d, want %d", attrs["http.status_code"], http.StatusCreated)
+	}
+	if attrs["http.response_content_length"] != int64(len("hello")) {
+		t.Fatalf("http.response_content_length = %d, want %d", attrs["http.response_content_length"], len("hello"))
+	}
+}
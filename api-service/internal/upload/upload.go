@@ -0,0 +1,33 @@
+// Package upload guards project file uploads against binary garbage that
+// would otherwise get shipped into .tex includes and blow up the
+// compiler.
+package upload
+
+import (
+	"net/http"
+	"strings"
+)
+
+// allowedBinaryTypes are the non-text MIME types LaTeX projects commonly
+// embed directly (figures, scanned pages, already-rendered output).
+var allowedBinaryTypes = map[string]bool{
+	"application/pdf":        true,
+	"image/png":              true,
+	"image/jpeg":             true,
+	"application/postscript": true,
+}
+
+// IsTextFile reports whether content is safe to accept into a project's
+// working tree. Empty content is treated as text (a new, empty file).
+// Otherwise content is sniffed with http.DetectContentType, and accepted
+// only if it's text/* or one of allowedBinaryTypes.
+func IsTextFile(content []byte) bool {
+	if len(content) == 0 {
+		return true
+	}
+	mediaType := http.DetectContentType(content)
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return strings.HasPrefix(mediaType, "text/") || allowedBinaryTypes[mediaType]
+}
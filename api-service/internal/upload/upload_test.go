@@ -0,0 +1,25 @@
+package upload
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsTextFile(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []byte
+		want bool
+	}{
+		{"empty", nil, true},
+		{"tex source", []byte("\\documentclass{article}\n"), true},
+		{"pdf", append([]byte("%PDF-1.4\n"), make([]byte, 16)...), true},
+		{"png", []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("x", 16)), true},
+		{"zip", append([]byte("PK\x03\x04"), make([]byte, 16)...), false},
+	}
+	for _, c := range cases {
+		if got := IsTextFile(c.in); got != c.want {
+			t.Errorf("IsTextFile(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
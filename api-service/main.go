@@ -2,23 +2,47 @@ package main
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/vvvigya/latex-editor/api-service/internal/auth"
+	"github.com/vvvigya/latex-editor/api-service/internal/collab"
+	"github.com/vvvigya/latex-editor/api-service/internal/comments"
+	"github.com/vvvigya/latex-editor/api-service/internal/compile"
+	"github.com/vvvigya/latex-editor/api-service/internal/docmodel"
+	"github.com/vvvigya/latex-editor/api-service/internal/format"
+	"github.com/vvvigya/latex-editor/api-service/internal/httplog"
+	"github.com/vvvigya/latex-editor/api-service/internal/httpx"
+	"github.com/vvvigya/latex-editor/api-service/internal/jsonpatch"
+	"github.com/vvvigya/latex-editor/api-service/internal/latex"
+	"github.com/vvvigya/latex-editor/api-service/internal/logparser"
+	"github.com/vvvigya/latex-editor/api-service/internal/metrics"
+	"github.com/vvvigya/latex-editor/api-service/internal/preview/svg"
+	"github.com/vvvigya/latex-editor/api-service/internal/snapshot"
+	"github.com/vvvigya/latex-editor/api-service/internal/storage"
+	"github.com/vvvigya/latex-editor/api-service/internal/tracing"
+	"github.com/vvvigya/latex-editor/api-service/internal/upload"
 )
 
 const (
@@ -37,6 +61,31 @@ var (
 	}
 	projectState = newProjectState()
 	serverStart  = time.Now()
+
+	compileQueue  compile.Queue
+	compileWorker *compile.Worker
+
+	fileStorage storage.FS
+
+	snapshotStore *snapshot.Store
+
+	docRegistry *collab.Registry
+	collabHub   = collab.NewHub()
+
+	authStore  = auth.NewStore()
+	authSigner *auth.Signer
+
+	formatConfig = format.Config{FormatOnSave: envOr("FORMAT_ON_SAVE", "true") == "true"}
+
+	commentsStore comments.Store = comments.NewMemStore()
+
+	svgCache  = svg.NewCache()
+	svgConfig = svg.Config{
+		LightStroke: envOr("SVG_COLOR_LIGHT_SCHEME", svg.DefaultConfig.LightStroke),
+		DarkStroke:  envOr("SVG_COLOR_DARK_SCHEME", svg.DefaultConfig.DarkStroke),
+	}
+
+	logger *zap.Logger
 )
 
 type Project struct {
@@ -155,12 +204,53 @@ type CompileCanceled struct {
 	JobID     string `json:"jobId"`
 	Revision  string `json:"revision"`
 }
+
+// CompileDiagnosticsMsg carries the structured findings logparser.Parse
+// extracted from a job's log, sent alongside compileSucceeded/compileFailed.
+type CompileDiagnosticsMsg struct {
+	Type        string                 `json:"type"` // "compileDiagnostics"
+	ProjectID   string                 `json:"projectId"`
+	TS          string                 `json:"ts"`
+	JobID       string                 `json:"jobId"`
+	Revision    string                 `json:"revision"`
+	Diagnostics []logparser.Diagnostic `json:"diagnostics"`
+}
 type WSPong struct {
 	Type      string `json:"type"` // "pong"
 	ProjectID string `json:"projectId"`
 	TS        string `json:"ts"`
 }
 
+// WSOpMessage is a collab.Change sent by the client (as "docUpdate") or
+// the server's transformed reply broadcast to other subscribers.
+type WSOpMessage struct {
+	Type         string      `json:"type"` // "docUpdate"
+	ProjectID    string      `json:"projectId"`
+	TS           string      `json:"ts"`
+	EntryFile    string      `json:"entryFile"`
+	BaseRevision int         `json:"baseRevision"`
+	Ops          []collab.Op `json:"ops"`
+	Revision     int         `json:"revision,omitempty"`
+}
+
+type WSSnapshot struct {
+	Type      string `json:"type"` // "snapshot"
+	ProjectID string `json:"projectId"`
+	TS        string `json:"ts"`
+	EntryFile string `json:"entryFile"`
+	Content   string `json:"content"`
+	Revision  int    `json:"revision"`
+}
+
+type WSPresence struct {
+	Type      string        `json:"type"` // "presence"
+	ProjectID string        `json:"projectId"`
+	TS        string        `json:"ts"`
+	EntryFile string        `json:"entryFile"`
+	ClientID  string        `json:"clientId"`
+	Cursor    *CursorCursor `json:"cursor,omitempty"`
+}
+
 type PutFile struct {
 	Path    string `json:"path"`
 	Content string `json:"content"`
@@ -175,8 +265,16 @@ type SavedFile struct {
 	Bytes int    `json:"bytes"`
 }
 
+// RejectedFile explains why a file in a PutFiles request wasn't saved,
+// e.g. because upload.IsTextFile rejected its sniffed content type.
+type RejectedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
 type SavedFilesResp struct {
-	Saved []SavedFile `json:"saved"`
+	Saved    []SavedFile    `json:"saved"`
+	Rejected []RejectedFile `json:"rejected,omitempty"`
 }
 
 type CompileRequest struct {
@@ -251,17 +349,100 @@ func (pr *projectRegistry) setBuffer(projectID, entry, content string) {
 func main() {
 	log.Printf("api-service starting on :%s, LATEX_FILES_DIR=%s", defaultAPIPort, latexRoot)
 
+	var zapErr error
+	if envOr("LOG_FORMAT", "json") == "console" {
+		logger, zapErr = zap.NewDevelopment()
+	} else {
+		logger, zapErr = zap.NewProduction()
+	}
+	if zapErr != nil {
+		log.Fatalf("failed to init logger: %v", zapErr)
+	}
+	defer logger.Sync()
+	httpx.Logger = logger
+
+	shutdownTracing, tracingErr := tracing.Init(context.Background(), "latex-editor-api")
+	if tracingErr != nil {
+		log.Fatalf("failed to init tracing: %v", tracingErr)
+	}
+	defer shutdownTracing(context.Background())
+
 	if err := os.MkdirAll(latexRoot, 0o755); err != nil {
 		log.Fatalf("failed to ensure latex root: %v", err)
 	}
 
+	var err error
+	fileStorage, err = storage.New(
+		envOr("STORAGE_BACKEND", "local"),
+		latexRoot,
+		storage.S3Config{
+			Endpoint:  envOr("S3_ENDPOINT", ""),
+			Bucket:    envOr("S3_BUCKET", ""),
+			AccessKey: envOr("S3_ACCESS_KEY", ""),
+			SecretKey: envOr("S3_SECRET_KEY", ""),
+			UseSSL:    envOr("S3_USE_SSL", "true") == "true",
+		},
+		storage.GCSConfig{
+			Bucket:          envOr("GCS_BUCKET", ""),
+			CredentialsFile: envOr("GCS_CREDENTIALS_FILE", ""),
+		},
+	)
+	if err != nil {
+		log.Fatalf("failed to init storage backend: %v", err)
+	}
+
+	snapshotStore = snapshot.NewStore(fileStorage)
+	go snapshotGCLoop(context.Background())
+
+	compileQueue, err = compile.NewQueue(
+		envOr("COMPILE_BACKEND", "fs"),
+		latexRoot,
+		envOr("COMPILE_REDIS_ADDR", "localhost:6379"),
+		envOr("COMPILE_REDIS_KEY", "compile:queue"),
+	)
+	if err != nil {
+		log.Fatalf("failed to init compile queue: %v", err)
+	}
+	docRegistry = collab.NewRegistry(
+		func(projectID, entryFile string) string {
+			return filepath.Join(projectDir(projectID), ".collab", filepath.FromSlash(entryFile))
+		},
+		func(projectID, entryFile string) string {
+			key, _ := storageKey(projectID, entryFile)
+			rc, err := fileStorage.Open(context.Background(), key)
+			if err != nil {
+				return ""
+			}
+			defer rc.Close()
+			b, _ := io.ReadAll(rc)
+			return string(b)
+		},
+	)
+
+	compileWorker = compile.NewWorker(compileQueue, projectDir, compile.DefaultLimits)
+	compileWorker.FetchFiles = fetchProjectFiles
+	compileWorker.PublishFile = publishCompiledFile
+	compileWorker.OnSuccess = snapshotOnCompileSuccess
+	workerCtx, cancelWorker := context.WithCancel(context.Background())
+	defer cancelWorker()
+	if !isSimulationEnabled() {
+		go compileWorker.Run(workerCtx)
+	}
+
+	authSigner = auth.NewSigner(envOr("AUTH_JWT_SECRET", "dev-secret-change-me"))
+	upgrader.CheckOrigin = auth.AllowOrigin(strings.Split(envOr("ALLOWED_ORIGINS", ""), ","))
+
 	mux := http.NewServeMux()
 
 	mux.HandleFunc(apiPrefix+"/health", handleHealth)
 	mux.HandleFunc(apiPrefix+"/version", handleVersion)
+	mux.HandleFunc(apiPrefix+"/auth/register", handleRegister)
+	mux.HandleFunc(apiPrefix+"/auth/login", handleLogin)
 	mux.HandleFunc(apiPrefix+"/projects", routeProjects)
 	mux.HandleFunc(apiPrefix+"/projects/import", handleImportProject)
 	mux.HandleFunc(apiPrefix+"/projects/", routeProjectByID)
+	mux.HandleFunc(apiPrefix+"/documents/", routeDocumentByID)
+	mux.HandleFunc(apiPrefix+"/comments/", routeCommentByID)
 
 	mux.HandleFunc(filesPrefix+"/", handleFiles)
 	mux.HandleFunc(wsPrefix+"/projects/", handleWSProjects)
@@ -269,34 +450,170 @@ func main() {
 	// Legacy health for frontend
 	mux.HandleFunc("/health", handleHealth)
 
+	mux.Handle("/metrics", metrics.Handler())
+
 	addr := ":" + defaultAPIPort
-	if err := http.ListenAndServe(addr, loggingMiddleware(mux)); err != nil {
+	requestLogger := httplog.Middleware(logger, genToken, recordHTTPMetrics)
+	traced := tracing.Middleware(func(r *http.Request) string { return routeLabel(r.URL.Path) })(mux)
+	handler := auth.Middleware(authSigner)(requestLogger(traced))
+	if err := http.ListenAndServe(addr, handler); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
 
+// snapshotGCLoop periodically prunes blobs no snapshot manifest
+// references anymore, across every project currently known in memory.
+func snapshotGCLoop(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			projectState.mu.RLock()
+			ids := make([]string, 0, len(projectState.projects))
+			for id := range projectState.projects {
+				ids = append(ids, id)
+			}
+			projectState.mu.RUnlock()
+			for _, id := range ids {
+				if removed, err := snapshotStore.GC(ctx, id); err != nil {
+					log.Printf("snapshot gc failed for project %s: %v", id, err)
+				} else if removed > 0 {
+					log.Printf("snapshot gc: removed %d unreferenced blobs for project %s", removed, id)
+				}
+			}
+		}
+	}
+}
+
+// snapshotAuthor returns the best identifier we have for whoever is
+// making this request, for attribution on the snapshot manifest.
+func snapshotAuthor(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok && p.Email != "" {
+		return p.Email
+	}
+	return ""
+}
+
+// snapshotOnCompileSuccess is compileWorker's OnSuccess hook: it records
+// the post-compile working tree as a new snapshot.
+func snapshotOnCompileSuccess(ctx context.Context, projectID string) {
+	rev := projectState.getLatestRevision(projectID)
+	if _, err := snapshotStore.Create(ctx, projectID, snapshot.Options{Message: "successful compile", Revision: rev}); err != nil {
+		log.Printf("snapshot create failed for project %s: %v", projectID, err)
+	}
+}
+
+// HealthResponse reports process liveness and, separately, whether the
+// configured storage backend (local disk, S3/GCS, or an in-memory store
+// in tests) is currently reachable — so a load balancer or on-call dashboard
+// can tell "process is up but storage is down" apart from "all clear".
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Storage string `json:"storage"`
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Cache-Control", "no-store")
-	w.WriteHeader(http.StatusOK)
-	io.WriteString(w, "ok")
+	storageStatus := "unknown"
+	status := http.StatusOK
+	if fileStorage != nil {
+		if err := fileStorage.Ping(r.Context()); err != nil {
+			storageStatus = "unreachable"
+			status = http.StatusServiceUnavailable
+		} else {
+			storageStatus = "ok"
+		}
+	}
+	httpx.Respond(w, r, status, HealthResponse{Status: "ok", Storage: storageStatus})
 }
 
 func handleVersion(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	info := map[string]string{
 		"api":    version,
 		"uptime": time.Since(serverStart).String(),
 	}
-	writeJSON(w, http.StatusOK, info)
+	httpx.Respond(w, r, http.StatusOK, info)
 }
 
-func writeJSON(w http.ResponseWriter, status int, v any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "no-store")
-	w.WriteHeader(status)
-	if v != nil {
-		json.NewEncoder(w).Encode(v)
+type AuthCredentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type AuthResponse struct {
+	Token string `json:"token"`
+	User  struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+const userTokenTTL = 7 * 24 * time.Hour
+
+func handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+		return
+	}
+	var creds AuthCredentials
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&creds); err != nil || creds.Email == "" || creds.Password == "" {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"email and password required", "bad_request"})
+		return
+	}
+	u, err := authStore.Register(uuid(), creds.Email, creds.Password)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusConflict, ErrorBody{err.Error(), "email_taken"})
+		return
+	}
+	respondWithToken(w, r, u)
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+		return
+	}
+	var creds AuthCredentials
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&creds); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	u, err := authStore.Authenticate(creds.Email, creds.Password)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusUnauthorized, ErrorBody{err.Error(), "invalid_credentials"})
+		return
+	}
+	respondWithToken(w, r, u)
+}
+
+func respondWithToken(w http.ResponseWriter, r *http.Request, u *auth.User) {
+	token, err := authSigner.IssueUserToken(u.ID, u.Email, userTokenTTL)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to issue token", "internal_error"})
+		return
+	}
+	resp := AuthResponse{Token: token}
+	resp.User.ID = u.ID
+	resp.User.Email = u.Email
+	httpx.Respond(w, r, http.StatusOK, resp)
+}
+
+// requireRole enforces that the caller is a member of projectID at role
+// min or later, writing a 403 and returning false if not. Projects
+// with no recorded membership (created before auth was wired up, or by
+// an anonymous caller) remain open, matching the service's prior
+// behavior until their owner explicitly shares or locks them down.
+func requireRole(w http.ResponseWriter, r *http.Request, projectID string, min auth.Role) bool {
+	if !authStore.HasMembers(projectID) {
+		return true
 	}
+	if !auth.Allow(r.Context(), authStore, projectID, min) {
+		httpx.Respond(w, r, http.StatusForbidden, ErrorBody{"insufficient project role", "forbidden"})
+		return false
+	}
+	return true
 }
 
 func getProject(projectID string) *Project {
@@ -305,12 +622,17 @@ func getProject(projectID string) *Project {
 	return projectState.projects[projectID]
 }
 
-func defaultTemplate(t string) string {
+// defaultTemplate renders the seed main.tex for a new project. title comes
+// straight from the caller-supplied project name, so it's passed through
+// latex.Escape (via the template's "escape" func) rather than interpolated
+// directly, in case it contains LaTeX-special characters.
+func defaultTemplate(t, title string) string {
+	var src string
 	switch strings.ToLower(t) {
 	case "report":
-		return `\documentclass{report}
+		src = `\documentclass{report}
 \begin{document}
-\title{My Report}
+\title{ {{- .Title | escape -}} }
 \author{Author}
 \maketitle
 \chapter{Introduction}
@@ -318,9 +640,9 @@ This is the introduction.
 \end{document}
 `
 	case "beamer":
-		return `\documentclass{beamer}
+		src = `\documentclass{beamer}
 \usetheme{Madrid}
-\title{My Presentation}
+\title{ {{- .Title | escape -}} }
 \author{Author}
 \date{\today}
 \begin{document}
@@ -338,6 +660,13 @@ Hello, LaTeX!
 \end{document}
 `
 	}
+
+	tmpl := template.Must(template.New("seed").Funcs(latex.FuncMap()).Parse(src))
+	var out strings.Builder
+	if err := tmpl.Execute(&out, struct{ Title string }{Title: title}); err != nil {
+		return src
+	}
+	return out.String()
 }
 
 func genToken() string {
@@ -351,24 +680,24 @@ func routeProjects(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		handleListProjects(w, r)
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+		httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
 	}
 }
 
 func handleImportProject(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+		httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
 		return
 	}
 
 	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB limit
-		writeJSON(w, http.StatusBadRequest, ErrorBody{"invalid form", "bad_request"})
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid form", "bad_request"})
 		return
 	}
 
 	file, handler, err := r.FormFile("file")
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, ErrorBody{"missing file", "bad_request"})
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"missing file", "bad_request"})
 		return
 	}
 	defer file.Close()
@@ -389,53 +718,45 @@ func handleImportProject(w http.ResponseWriter, r *http.Request) {
 
 	root := projectDir(id)
 	if err := os.MkdirAll(root, 0o755); err != nil {
-		writeJSON(w, http.StatusInternalServerError, ErrorBody{"failed to create project directory", "internal_error"})
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to create project directory", "internal_error"})
 		return
 	}
 
 	zipReader, err := zip.NewReader(file, handler.Size)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, ErrorBody{"invalid zip file", "bad_zip"})
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid zip file", "bad_zip"})
 		return
 	}
 
 	for _, f := range zipReader.File {
-		fpath := filepath.Join(root, f.Name)
-
-		if !strings.HasPrefix(fpath, filepath.Clean(root)+string(os.PathSeparator)) {
-			writeJSON(w, http.StatusBadRequest, ErrorBody{"invalid file path in zip", "bad_zip_path"})
-			return
-		}
-
 		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, os.ModePerm)
 			continue
 		}
 
-		if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			writeJSON(w, http.StatusInternalServerError, ErrorBody{"failed to create directory from zip", "internal_error"})
+		key, ok := storageKey(id, f.Name)
+		if !ok {
+			httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid file path in zip", "bad_zip_path"})
 			return
 		}
 
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+		rc, err := f.Open()
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, ErrorBody{"failed to create file from zip", "internal_error"})
+			httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to open file in zip", "internal_error"})
 			return
 		}
-
-		rc, err := f.Open()
+		content, err := io.ReadAll(rc)
+		rc.Close()
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, ErrorBody{"failed to open file in zip", "internal_error"})
+			httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to read file in zip", "internal_error"})
+			return
+		}
+		if !upload.IsTextFile(content) {
+			httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"zip contains an unsupported file type: " + f.Name, "unsupported_file_type"})
 			return
 		}
 
-		_, err = io.Copy(outFile, rc)
-
-		outFile.Close()
-		rc.Close()
-
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, ErrorBody{"failed to write file from zip", "internal_error"})
+		if err := fileStorage.WriteFile(r.Context(), key, bytes.NewReader(content), int64(len(content))); err != nil {
+			httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to write file from zip", "internal_error"})
 			return
 		}
 	}
@@ -446,7 +767,12 @@ func handleImportProject(w http.ResponseWriter, r *http.Request) {
 	projectState.projects[id] = p
 	projectState.mu.Unlock()
 
-	writeJSON(w, http.StatusCreated, p)
+	if principal, ok := auth.FromContext(r.Context()); ok && principal.UserID != "" {
+		authStore.AddMember(id, principal.UserID, auth.RoleOwner)
+	}
+	updateProjectFilesBytesMetric(r.Context(), id)
+
+	httpx.Respond(w, r, http.StatusCreated, p)
 }
 
 func createCompileDirs(root string) {
@@ -459,7 +785,7 @@ func createCompileDirs(root string) {
 func routeProjectByID(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.TrimPrefix(r.URL.Path, apiPrefix+"/projects/"), "/")
 	if len(parts) == 0 || parts[0] == "" {
-		writeJSON(w, http.StatusBadRequest, ErrorBody{"missing projectId", "invalid_request"})
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"missing projectId", "invalid_request"})
 		return
 	}
 	projectID := parts[0]
@@ -467,11 +793,17 @@ func routeProjectByID(w http.ResponseWriter, r *http.Request) {
 	if len(parts) == 1 {
 		switch r.Method {
 		case http.MethodGet:
+			if !requireRole(w, r, projectID, auth.RoleViewer) {
+				return
+			}
 			handleGetProject(w, r, projectID)
 		case http.MethodDelete:
+			if !requireRole(w, r, projectID, auth.RoleOwner) {
+				return
+			}
 			handleDeleteProject(w, r, projectID)
 		default:
-			writeJSON(w, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
 		}
 		return
 	}
@@ -480,180 +812,983 @@ func routeProjectByID(w http.ResponseWriter, r *http.Request) {
 	case "files":
 		switch r.Method {
 		case http.MethodGet:
+			if !requireRole(w, r, projectID, auth.RoleViewer) {
+				return
+			}
 			handleGetFile(w, r, projectID)
 		case http.MethodPut:
+			if !requireRole(w, r, projectID, auth.RoleEditor) {
+				return
+			}
 			handlePutFiles(w, r, projectID)
 		default:
-			writeJSON(w, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
 		}
 	case "compile":
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		if len(parts) == 4 && parts[3] == "diagnostics" {
+			handleCompileDiagnostics(w, r, projectID, parts[2])
+			return
+		}
+		if r.Method == http.MethodDelete {
+			handleCancelCompile(w, r, projectID)
+			return
+		}
 		handleCompile(w, r, projectID)
 	case "download":
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
 		handleProjectDownload(w, r, projectID)
+	case "shares":
+		if r.Method != http.MethodPost {
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			return
+		}
+		if !requireRole(w, r, projectID, auth.RoleOwner) {
+			return
+		}
+		handleCreateShare(w, r, projectID)
+	case "snapshots":
+		handleProjectSnapshots(w, r, projectID, parts)
+	case "format":
+		if r.Method != http.MethodPost {
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			return
+		}
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		handleFormatFile(w, r, projectID)
+	case "preview":
+		if r.Method != http.MethodGet {
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			return
+		}
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		handlePreviewDiagrams(w, r, projectID)
 	default:
-		writeJSON(w, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
 	}
 }
 
-// Stream a ZIP of the entire project directory
-func handleProjectDownload(w http.ResponseWriter, r *http.Request, projectID string) {
-	p := getProject(projectID)
-	if p == nil {
-		writeJSON(w, http.StatusNotFound, ErrorBody{"not found", "not_found"})
-		return
+// parseDocumentID splits the opaque {id} used by /api/documents/{id}
+// (a projectID and URL-escaped entry file joined by ":") into its parts,
+// or reports ok=false for a malformed id.
+func parseDocumentID(id string) (projectID, entryFile string, ok bool) {
+	i := strings.IndexByte(id, ':')
+	if i < 0 {
+		return "", "", false
 	}
-	root := projectDir(projectID)
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", p.Name+".zip"))
+	entry, err := url.QueryUnescape(id[i+1:])
+	if err != nil {
+		return "", "", false
+	}
+	return id[:i], entry, true
+}
 
-	zw := zip.NewWriter(w)
-	defer zw.Close()
+// routeDocumentByID dispatches /api/documents/{id}, where {id} is a
+// documentID. Currently the only verb is PATCH, for applying an RFC 6902
+// JSON Patch to the document's structured view (see internal/docmodel).
+func routeDocumentByID(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, apiPrefix+"/documents/")
+	parts := strings.SplitN(rest, "/", 2)
+	projectID, entryFile, ok := parseDocumentID(parts[0])
+	if !ok {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"malformed document id", "invalid_request"})
+		return
+	}
 
-	filepath.WalkDir(root, func(pth string, d os.DirEntry, err error) error {
-		if err != nil || pth == root {
-			return nil
-		}
-		rel, _ := filepath.Rel(root, pth)
-		// Skip compile artifacts
-		if strings.HasPrefix(rel, "compile") {
-			return nil
+	if len(parts) == 1 {
+		if !requireRole(w, r, projectID, auth.RoleEditor) {
+			return
 		}
-		if d.IsDir() {
-			_, err := zw.Create(rel + "/")
-			return err
+		switch r.Method {
+		case http.MethodPatch:
+			handlePatchDocument(w, r, projectID, entryFile)
+		default:
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
 		}
-		f, err := os.Open(pth)
-		if err != nil {
-			return nil
-		}
-		defer f.Close()
-		info, _ := f.Stat()
-		hdr, _ := zip.FileInfoHeader(info)
-		hdr.Name = rel
-		hdr.Method = zip.Deflate
-		wtr, _ := zw.CreateHeader(hdr)
-		_, _ = io.Copy(wtr, f)
-		return nil
-	})
-}
-
-func handleCreateProject(w http.ResponseWriter, r *http.Request) {
-	var body ProjectsCreateBody
-	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
-		writeJSON(w, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
 		return
 	}
-	if body.Name == "" {
-		body.Name = "Untitled Project"
-	}
 
-	id := uuid()
-	now := time.Now().UTC()
-	p := &Project{
-		ProjectID:    id,
-		Name:         body.Name,
-		CreatedAt:    now,
-		UpdatedAt:    now,
-		LastModified: now,
-		Engine:       "pdflatex",
-		EntryFile:    "main.tex",
+	switch parts[1] {
+	case "comments":
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			handleListComments(w, r, parts[0])
+		case http.MethodPost:
+			handlePostComment(w, r, parts[0])
+		default:
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+		}
+	default:
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
 	}
+}
 
-	root := projectDir(id)
-	os.MkdirAll(filepath.Join(root, "assets"), 0o755)
-	createCompileDirs(root)
-
-	seed := defaultTemplate(body.Template)
-	os.WriteFile(filepath.Join(root, "main.tex"), []byte(seed), 0o644)
-
-	projectState.mu.Lock()
-	projectState.projects[id] = p
-	projectState.mu.Unlock()
-
-	writeJSON(w, http.StatusCreated, p)
+// DiffSummary is a compact description of how much a PATCH changed,
+// returned instead of a full unified diff so the client doesn't have to
+// parse one just to show an "N lines changed" toast.
+type DiffSummary struct {
+	LinesChanged int `json:"linesChanged"`
+	BytesDelta   int `json:"bytesDelta"`
 }
 
-func handleListProjects(w http.ResponseWriter, r *http.Request) {
-	projectState.mu.RLock()
-	defer projectState.mu.RUnlock()
-	var list []*Project
-	for _, p := range projectState.projects {
-		list = append(list, p)
+func summarizeDiff(oldContent, newContent string) DiffSummary {
+	oldLines := strings.Split(oldContent, "\n")
+	newLines := strings.Split(newContent, "\n")
+	n := len(oldLines)
+	if len(newLines) > n {
+		n = len(newLines)
+	}
+	changed := 0
+	for i := 0; i < n; i++ {
+		var o, nw string
+		if i < len(oldLines) {
+			o = oldLines[i]
+		}
+		if i < len(newLines) {
+			nw = newLines[i]
+		}
+		if o != nw {
+			changed++
+		}
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"projects": list})
+	return DiffSummary{LinesChanged: changed, BytesDelta: len(newContent) - len(oldContent)}
 }
 
-func handleGetProject(w http.ResponseWriter, r *http.Request, projectID string) {
-	p := getProject(projectID)
-	if p == nil {
-		writeJSON(w, http.StatusNotFound, ErrorBody{"not found", "not_found"})
-		return
-	}
-	writeJSON(w, http.StatusOK, p)
+// PatchDocumentResponse reports the outcome of a successful JSON Patch.
+type PatchDocumentResponse struct {
+	Revision    int         `json:"revision"`
+	DiffSummary DiffSummary `json:"diffSummary"`
 }
 
-func handleDeleteProject(w http.ResponseWriter, r *http.Request, projectID string) {
-	if getProject(projectID) == nil {
-		w.WriteHeader(http.StatusNoContent)
+// handlePatchDocument applies an RFC 6902 JSON Patch to a document's
+// structured view (internal/docmodel's preamble/sections/metadata)
+// instead of requiring the whole .tex body to be re-uploaded for a small
+// edit. A stale If-Match revision is rejected with 409 before the patch
+// is even decoded; the patch itself is applied atomically — any failed
+// operation, including a "test", rejects the whole patch and leaves the
+// document untouched.
+func handlePatchDocument(w http.ResponseWriter, r *http.Request, projectID, entryFile string) {
+	doc, err := docRegistry.Get(projectID, entryFile)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"document not found", "not_found"})
 		return
 	}
-	os.RemoveAll(projectDir(projectID))
-	projectState.mu.Lock()
-	delete(projectState.projects, projectID)
-	delete(projectState.revisions, projectID)
-	delete(projectState.buffers, projectID)
-	projectState.mu.Unlock()
-	w.WriteHeader(http.StatusNoContent)
-}
+	content, revision := doc.Snapshot()
 
-func handleGetFile(w http.ResponseWriter, r *http.Request, projectID string) {
-	pth := r.URL.Query().Get("path")
-	full, ok := safeJoin(projectDir(projectID), pth)
-	if !ok {
-		writeJSON(w, http.StatusBadRequest, ErrorBody{"invalid path", "invalid_path"})
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		want, err := strconv.Atoi(ifMatch)
+		if err != nil || want != revision {
+			httpx.Respond(w, r, http.StatusConflict, ErrorBody{"document revision does not match If-Match", "revision_conflict"})
+			return
+		}
+	}
+
+	var patch jsonpatch.Patch
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&patch); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
 		return
 	}
-	b, err := os.ReadFile(full)
+
+	view, err := toGeneric(docmodel.Parse(content))
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, ErrorBody{"file not found", "not_found"})
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to build document view", "internal_error"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"path": pth, "content": string(b)})
-}
-
-func handlePutFiles(w http.ResponseWriter, r *http.Request, projectID string) {
-	var body PutFilesBody
-	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20)).Decode(&body); err != nil {
-		writeJSON(w, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+	patched, err := jsonpatch.Apply(view, patch)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusUnprocessableEntity, ErrorBody{err.Error(), "invalid_patch"})
 		return
 	}
-	root := projectDir(projectID)
-	var saved []SavedFile
-	for _, f := range body.Files {
-		full, ok := safeJoin(root, f.Path)
-		if !ok {
-			continue
-		}
-		os.MkdirAll(filepath.Dir(full), 0o755)
-		os.WriteFile(full, []byte(f.Content), 0o644)
-		saved = append(saved, SavedFile{Path: f.Path, Bytes: len(f.Content)})
+	var newModel docmodel.Doc
+	if err := fromGeneric(patched, &newModel); err != nil {
+		httpx.Respond(w, r, http.StatusUnprocessableEntity, ErrorBody{"patched document has the wrong shape", "invalid_patch"})
+		return
 	}
-	writeJSON(w, http.StatusOK, SavedFilesResp{Saved: saved})
-}
+	newContent := newModel.Render()
 
-func handleCompile(w http.ResponseWriter, r *http.Request, projectID string) {
-	p := getProject(projectID)
-	if p == nil {
-		writeJSON(w, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+	ops := opsFromContentDiff(content, newContent)
+	_, newRevision, err := doc.Submit(collab.Change{BaseRevision: revision, Ops: ops})
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to apply patch", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, PatchDocumentResponse{
+		Revision:    newRevision,
+		DiffSummary: summarizeDiff(content, newContent),
+	})
+}
+
+// toGeneric/fromGeneric round-trip a typed value through encoding/json so
+// jsonpatch.Apply (which only understands map[string]any/[]any/scalars)
+// can operate on it.
+func toGeneric(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func fromGeneric(v any, out any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// opsFromContentDiff builds a retain/delete/insert Op sequence turning
+// oldContent into newContent, for collab.Document.Submit. It only trims
+// the common prefix/suffix rather than computing a minimal diff: the edit
+// here is driven by a structured JSON Patch, not free-form typing, so
+// there's no OT-conflict-minimization benefit to a smarter diff.
+func opsFromContentDiff(oldContent, newContent string) []collab.Op {
+	prefix := commonPrefixLen(oldContent, newContent)
+	suffix := commonSuffixLen(oldContent[prefix:], newContent[prefix:])
+
+	var ops []collab.Op
+	if prefix > 0 {
+		ops = append(ops, collab.Op{Kind: collab.OpRetain, N: prefix})
+	}
+	if deleted := len(oldContent) - prefix - suffix; deleted > 0 {
+		ops = append(ops, collab.Op{Kind: collab.OpDelete, N: deleted})
+	}
+	if inserted := newContent[prefix : len(newContent)-suffix]; inserted != "" {
+		ops = append(ops, collab.Op{Kind: collab.OpInsert, Text: inserted})
+	}
+	if suffix > 0 {
+		ops = append(ops, collab.Op{Kind: collab.OpRetain, N: suffix})
+	}
+	return ops
+}
+
+func commonPrefixLen(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	i := 0
+	for i < n && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func commonSuffixLen(a, b string) int {
+	i, j, n := len(a), len(b), 0
+	for i > 0 && j > 0 && a[i-1] == b[j-1] {
+		i--
+		j--
+		n++
+	}
+	return n
+}
+
+// CommentRequest is the body for posting a comment or a reply.
+type CommentRequest struct {
+	Author  string         `json:"author"`
+	Message string         `json:"message"`
+	Anchor  comments.Anchor `json:"anchor"`
+}
+
+// CommentListResponse lists a document's comments, with anchors
+// recomputed against its current content where the document has changed
+// since they were posted.
+type CommentListResponse struct {
+	Comments []comments.Comment `json:"comments"`
+}
+
+// handlePostComment creates a new top-level (ParentID-less) comment
+// anchored to a line in the document named by documentID.
+func handlePostComment(w http.ResponseWriter, r *http.Request, documentID string) {
+	projectID, entryFile, ok := parseDocumentID(documentID)
+	if !ok {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"malformed document id", "invalid_request"})
+		return
+	}
+	var body CommentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	if body.Message == "" {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"message is required", "invalid_request"})
+		return
+	}
+
+	contentHash := ""
+	if docRegistry != nil {
+		if doc, err := docRegistry.Get(projectID, entryFile); err == nil {
+			content, _ := doc.Snapshot()
+			contentHash = comments.HashContent(content)
+		}
+	}
+
+	c, err := commentsStore.Create(r.Context(), comments.Comment{
+		DocumentID:  documentID,
+		Author:      body.Author,
+		PostedAt:    time.Now().UTC(),
+		Anchor:      body.Anchor,
+		Message:     body.Message,
+		ContentHash: contentHash,
+	})
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to save comment", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusCreated, c)
+}
+
+// handleListComments returns every comment on a document, relocating
+// each comment's anchor to its new line if the document has changed
+// since the comment was posted (see comments.RecomputeAnchor).
+func handleListComments(w http.ResponseWriter, r *http.Request, documentID string) {
+	projectID, entryFile, ok := parseDocumentID(documentID)
+	if !ok {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"malformed document id", "invalid_request"})
+		return
+	}
+	list, err := commentsStore.ListByDocument(r.Context(), documentID)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to list comments", "internal_error"})
+		return
+	}
+
+	if docRegistry != nil {
+		if doc, err := docRegistry.Get(projectID, entryFile); err == nil {
+			content, _ := doc.Snapshot()
+			hash := comments.HashContent(content)
+			for i, c := range list {
+				if c.ContentHash != "" && c.ContentHash != hash {
+					list[i].Anchor = comments.RecomputeAnchor(content, c.Anchor)
+				}
+			}
+		}
+	}
+	httpx.Respond(w, r, http.StatusOK, CommentListResponse{Comments: list})
+}
+
+// routeCommentByID dispatches /api/comments/{id}(/replies)?. Every branch
+// looks the comment up first so it can authorize against the project the
+// comment's document belongs to.
+func routeCommentByID(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, apiPrefix+"/comments/")
+	parts := strings.SplitN(rest, "/", 2)
+	if parts[0] == "" {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"missing comment id", "invalid_request"})
+		return
+	}
+
+	c, err := commentsStore.Get(r.Context(), parts[0])
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"comment not found", "not_found"})
+		return
+	}
+	projectID, _, ok := parseDocumentID(c.DocumentID)
+	if !ok {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"comment has a malformed document id", "internal_error"})
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "replies" {
+		if r.Method != http.MethodPost {
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			return
+		}
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		handlePostReply(w, r, c)
+		return
+	}
+	if len(parts) > 1 {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		handleResolveComment(w, r, c)
+	case http.MethodDelete:
+		if !requireRole(w, r, projectID, auth.RoleEditor) {
+			return
+		}
+		handleDeleteComment(w, r, c.ID)
+	default:
+		httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+	}
+}
+
+// handlePostReply adds a threaded reply under parent, inheriting its
+// anchor rather than taking one of its own.
+func handlePostReply(w http.ResponseWriter, r *http.Request, parent comments.Comment) {
+	var body CommentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	if body.Message == "" {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"message is required", "invalid_request"})
+		return
+	}
+	reply, err := commentsStore.Create(r.Context(), comments.Comment{
+		DocumentID:  parent.DocumentID,
+		Author:      body.Author,
+		PostedAt:    time.Now().UTC(),
+		Anchor:      parent.Anchor,
+		Message:     body.Message,
+		ParentID:    parent.ID,
+		ContentHash: parent.ContentHash,
+	})
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to save reply", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusCreated, reply)
+}
+
+// ResolveCommentRequest toggles a comment's resolved state.
+type ResolveCommentRequest struct {
+	Resolved bool `json:"resolved"`
+}
+
+func handleResolveComment(w http.ResponseWriter, r *http.Request, c comments.Comment) {
+	var body ResolveCommentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	c.Resolved = body.Resolved
+	if err := commentsStore.Update(r.Context(), c); err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to update comment", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, c)
+}
+
+func handleDeleteComment(w http.ResponseWriter, r *http.Request, id string) {
+	if err := commentsStore.Delete(r.Context(), id); err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"comment not found", "not_found"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Stream a ZIP of the entire project by listing objects under
+// <id>/ and copying their readers straight into zip.Writer, so
+// this works the same whether files live on local disk or in S3.
+func handleProjectDownload(w http.ResponseWriter, r *http.Request, projectID string) {
+	p := getProject(projectID)
+	if p == nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+		return
+	}
+	objects, err := fileStorage.ReadDir(r.Context(), projectID+"/")
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to list project files", "internal_error"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", p.Name+".zip"))
+
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	prefix := projectID + "/"
+	for _, obj := range objects {
+		if obj.IsDir {
+			continue
+		}
+		rel := strings.TrimPrefix(obj.Name, prefix)
+		if rel == "" || strings.HasPrefix(rel, "compile/") {
+			continue
+		}
+		rc, err := fileStorage.Open(r.Context(), obj.Name)
+		if err != nil {
+			continue
+		}
+		hdr := &zip.FileHeader{Name: rel, Method: zip.Deflate, Modified: obj.ModTime}
+		wtr, err := zw.CreateHeader(hdr)
+		if err == nil {
+			io.Copy(wtr, rc)
+		}
+		rc.Close()
+	}
+}
+
+func handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	var body ProjectsCreateBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	if body.Name == "" {
+		body.Name = "Untitled Project"
+	}
+
+	id := uuid()
+	now := time.Now().UTC()
+	p := &Project{
+		ProjectID:    id,
+		Name:         body.Name,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		LastModified: now,
+		Engine:       "pdflatex",
+		EntryFile:    "main.tex",
+	}
+
+	createCompileDirs(projectDir(id))
+
+	seed := defaultTemplate(body.Template, body.Name)
+	fileStorage.WriteFile(r.Context(), id+"/main.tex", strings.NewReader(seed), int64(len(seed)))
+
+	projectState.mu.Lock()
+	projectState.projects[id] = p
+	projectState.mu.Unlock()
+
+	if principal, ok := auth.FromContext(r.Context()); ok && principal.UserID != "" {
+		authStore.AddMember(id, principal.UserID, auth.RoleOwner)
+	}
+	updateProjectFilesBytesMetric(r.Context(), id)
+
+	httpx.Respond(w, r, http.StatusCreated, p)
+}
+
+func handleListProjects(w http.ResponseWriter, r *http.Request) {
+	projectState.mu.RLock()
+	defer projectState.mu.RUnlock()
+	var list []*Project
+	for _, p := range projectState.projects {
+		list = append(list, p)
+	}
+	httpx.Respond(w, r, http.StatusOK, map[string]any{"projects": list})
+}
+
+func handleGetProject(w http.ResponseWriter, r *http.Request, projectID string) {
+	p := getProject(projectID)
+	if p == nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, p)
+}
+
+func handleDeleteProject(w http.ResponseWriter, r *http.Request, projectID string) {
+	if getProject(projectID) == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	fileStorage.RemoveAll(r.Context(), projectID+"/")
+	os.RemoveAll(projectDir(projectID)) // local compile scratch dirs always live on disk
+	authStore.RemoveProject(projectID)
+	projectState.mu.Lock()
+	delete(projectState.projects, projectID)
+	delete(projectState.revisions, projectID)
+	delete(projectState.buffers, projectID)
+	projectState.mu.Unlock()
+	metrics.ProjectFilesBytes.DeleteLabelValues(projectID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleGetFile(w http.ResponseWriter, r *http.Request, projectID string) {
+	pth := r.URL.Query().Get("path")
+	key, ok := storageKey(projectID, pth)
+	if !ok {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid path", "invalid_path"})
+		return
+	}
+	rc, err := fileStorage.Open(r.Context(), key)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"file not found", "not_found"})
+		return
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to read file", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, map[string]any{"path": pth, "content": string(b)})
+}
+
+func handlePutFiles(w http.ResponseWriter, r *http.Request, projectID string) {
+	var body PutFilesBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20)).Decode(&body); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	var saved []SavedFile
+	var rejected []RejectedFile
+	for _, f := range body.Files {
+		key, ok := storageKey(projectID, f.Path)
+		if !ok {
+			continue
+		}
+		if !upload.IsTextFile([]byte(f.Content)) {
+			rejected = append(rejected, RejectedFile{Path: f.Path, Reason: "unsupported file type"})
+			continue
+		}
+		content := f.Content
+		if formatConfig.FormatOnSave && strings.HasSuffix(f.Path, ".tex") {
+			content = formatTexOnSave(r.Context(), content)
+		}
+		if err := fileStorage.WriteFile(r.Context(), key, strings.NewReader(content), int64(len(content))); err != nil {
+			continue
+		}
+		saved = append(saved, SavedFile{Path: f.Path, Bytes: len(content)})
+	}
+	if len(saved) > 0 {
+		if _, err := snapshotStore.Create(r.Context(), projectID, snapshot.Options{
+			Message: "save", Author: snapshotAuthor(r), Revision: projectState.getLatestRevision(projectID),
+		}); err != nil {
+			log.Printf("snapshot create failed for project %s: %v", projectID, err)
+		}
+		updateProjectFilesBytesMetric(r.Context(), projectID)
+	}
+	httpx.Respond(w, r, http.StatusOK, SavedFilesResp{Saved: saved, Rejected: rejected})
+}
+
+// updateProjectFilesBytesMetric recomputes the project_files_bytes gauge
+// for projectID by summing the size of its non-reserved working-tree
+// files in fileStorage; it logs and gives up on listing errors rather
+// than failing the request that triggered it.
+func updateProjectFilesBytesMetric(ctx context.Context, projectID string) {
+	prefix := projectID + "/"
+	objects, err := fileStorage.ReadDir(ctx, prefix)
+	if err != nil {
+		log.Printf("project_files_bytes: list failed for project %s: %v", projectID, err)
+		return
+	}
+	var total int64
+	for _, obj := range objects {
+		if obj.IsDir {
+			continue
+		}
+		rel := strings.TrimPrefix(obj.Name, prefix)
+		if rel == "" || strings.HasPrefix(rel, "compile/") || rel == "output.pdf" {
+			continue
+		}
+		total += obj.Size
+	}
+	metrics.ProjectFilesBytes.WithLabelValues(projectID).Set(float64(total))
+}
+
+// formatTexOnSave runs latexfmt over content when FORMAT_ON_SAVE is
+// enabled; it falls back to the original content on any formatting error
+// so a formatter bug never blocks a save.
+func formatTexOnSave(ctx context.Context, content string) string {
+	edits, err := format.Format(ctx, []byte(content), format.Range{})
+	if err != nil || len(edits) == 0 {
+		return content
+	}
+	return string(format.Apply([]byte(content), edits))
+}
+
+// storageKey maps a project-relative path into the flat "projectID/rel"
+// key space fileStorage uses, rejecting any attempt to escape the
+// project's prefix (e.g. "../other-project/secret.tex").
+func storageKey(projectID, requested string) (string, bool) {
+	cleanRel := path.Clean("/" + filepath.ToSlash(requested))
+	if cleanRel == "/" {
+		return "", false
+	}
+	return projectID + cleanRel, true
+}
+
+func handleCompile(w http.ResponseWriter, r *http.Request, projectID string) {
+	p := getProject(projectID)
+	if p == nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
 		return
 	}
 	rev := projectState.getLatestRevision(projectID)
 	jobID, err := enqueueJob(projectID, p.EntryFile, p.Engine, rev, "rest")
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, ErrorBody{"enqueue failed", "internal_error"})
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"enqueue failed", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusAccepted, CompileAccepted{JobID: jobID, Revision: rev})
+}
+
+type CreateShareBody struct {
+	Role     auth.Role `json:"role"`     // "editor" or "viewer"
+	TTLHours int       `json:"ttlHours"` // 0 means no expiry
+}
+
+type ShareResponse struct {
+	Token string    `json:"token"`
+	Role  auth.Role `json:"role"`
+}
+
+// handleCreateShare mints a share-link JWT scoped to projectID and a
+// read-only or edit role, so the project can be opened via URL without
+// the recipient needing an account.
+func handleCreateShare(w http.ResponseWriter, r *http.Request, projectID string) {
+	var body CreateShareBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	if body.Role != auth.RoleEditor && body.Role != auth.RoleViewer {
+		body.Role = auth.RoleViewer
+	}
+	var ttl time.Duration
+	if body.TTLHours > 0 {
+		ttl = time.Duration(body.TTLHours) * time.Hour
+	}
+	token, err := authSigner.IssueShareToken(projectID, body.Role, ttl)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to issue share token", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusCreated, ShareResponse{Token: token, Role: body.Role})
+}
+
+func handleCancelCompile(w http.ResponseWriter, r *http.Request, projectID string) {
+	var body CancelRequest
+	json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body)
+	if body.JobID == "" {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"missing jobId", "bad_request"})
+		return
+	}
+	compileWorker.Cancel(body.JobID)
+	httpx.Respond(w, r, http.StatusAccepted, map[string]string{"jobId": body.JobID, "state": "canceling"})
+}
+
+// FormatFileRequest names the file to format and, optionally, a
+// sub-range; a zero Range formats the whole file.
+type FormatFileRequest struct {
+	Path  string       `json:"path"`
+	Range *format.Range `json:"range,omitempty"`
+}
+
+type FormatFileResponse struct {
+	Edits []format.TextEdit `json:"edits"`
+}
+
+// handleFormatFile runs latexfmt over one project file and returns the
+// minimal TextEdits the editor should apply; it never writes the file
+// itself, leaving that to the normal handlePutFiles save path.
+func handleFormatFile(w http.ResponseWriter, r *http.Request, projectID string) {
+	var body FormatFileRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid json", "bad_json"})
+		return
+	}
+	key, ok := storageKey(projectID, body.Path)
+	if !ok {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid path", "invalid_path"})
+		return
+	}
+	rc, err := fileStorage.Open(r.Context(), key)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"file not found", "not_found"})
+		return
+	}
+	defer rc.Close()
+	src, err := io.ReadAll(rc)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to read file", "internal_error"})
+		return
+	}
+
+	rng := format.Range{}
+	if body.Range != nil {
+		rng = *body.Range
+	}
+	edits, err := format.Format(r.Context(), src, rng)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"format failed", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, FormatFileResponse{Edits: edits})
+}
+
+// PreviewDiagramsResponse lists the TikZ/ASCII diagrams found in a file,
+// pre-rendered to SVG, in the order svg.Render returns them.
+type PreviewDiagramsResponse struct {
+	Fragments []svg.Fragment `json:"fragments"`
+}
+
+// handlePreviewDiagrams renders the TikZ pictures and fenced ASCII-art
+// boxes in ?path= to SVG, so the preview pane can show them without
+// waiting on a full PDF recompile. TikZ compilation is skipped (not
+// errored) when pdflatex/pdf2svg aren't on PATH.
+func handlePreviewDiagrams(w http.ResponseWriter, r *http.Request, projectID string) {
+	entry := r.URL.Query().Get("path")
+	if entry == "" {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"missing path", "invalid_request"})
+		return
+	}
+	key, ok := storageKey(projectID, entry)
+	if !ok {
+		httpx.Respond(w, r, http.StatusBadRequest, ErrorBody{"invalid path", "invalid_path"})
+		return
+	}
+	rc, err := fileStorage.Open(r.Context(), key)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"file not found", "not_found"})
+		return
+	}
+	defer rc.Close()
+	src, err := io.ReadAll(rc)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to read file", "internal_error"})
+		return
+	}
+
+	var compileTikz svg.TikzCompiler
+	if tikzToolsAvailable() {
+		compileTikz = svg.CompileTikz
+	}
+	frags, err := svg.Render(r.Context(), src, svgConfig, svgCache, compileTikz)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"preview render failed", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, PreviewDiagramsResponse{Fragments: frags})
+}
+
+// handleProjectSnapshots dispatches every /api/projects/{id}/snapshots...
+// route: parts is the full "/" split of the path, with parts[0]=projectID
+// and parts[1]="snapshots".
+func handleProjectSnapshots(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	switch len(parts) {
+	case 2:
+		if r.Method != http.MethodGet {
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			return
+		}
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		handleListSnapshots(w, r, projectID)
+	case 3:
+		if r.Method != http.MethodGet {
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			return
+		}
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		handleGetSnapshot(w, r, projectID, parts[2])
+	case 4:
+		switch parts[3] {
+		case "files":
+			if r.Method != http.MethodGet {
+				httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+				return
+			}
+			if !requireRole(w, r, projectID, auth.RoleViewer) {
+				return
+			}
+			handleSnapshotFile(w, r, projectID, parts[2])
+		case "restore":
+			if r.Method != http.MethodPost {
+				httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+				return
+			}
+			if !requireRole(w, r, projectID, auth.RoleEditor) {
+				return
+			}
+			handleRestoreSnapshot(w, r, projectID, parts[2])
+		default:
+			httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+		}
+	case 5:
+		if parts[3] != "diff" {
+			httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+			return
+		}
+		if r.Method != http.MethodGet {
+			httpx.Respond(w, r, http.StatusMethodNotAllowed, ErrorBody{"method not allowed", "method_not_allowed"})
+			return
+		}
+		if !requireRole(w, r, projectID, auth.RoleViewer) {
+			return
+		}
+		handleSnapshotDiff(w, r, projectID, parts[2], parts[4])
+	default:
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"not found", "not_found"})
+	}
+}
+
+func handleListSnapshots(w http.ResponseWriter, r *http.Request, projectID string) {
+	manifests, err := snapshotStore.List(r.Context(), projectID)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to list snapshots", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, map[string]any{"snapshots": manifests})
+}
+
+func handleGetSnapshot(w http.ResponseWriter, r *http.Request, projectID, snapshotID string) {
+	m, err := snapshotStore.Get(r.Context(), projectID, snapshotID)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"snapshot not found", "not_found"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, m)
+}
+
+func handleSnapshotFile(w http.ResponseWriter, r *http.Request, projectID, snapshotID string) {
+	pth := r.URL.Query().Get("path")
+	b, err := snapshotStore.FileAt(r.Context(), projectID, snapshotID, pth)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"file not found in snapshot", "not_found"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, map[string]any{"path": pth, "content": string(b)})
+}
+
+func handleSnapshotDiff(w http.ResponseWriter, r *http.Request, projectID, fromID, toID string) {
+	diffs, err := snapshotStore.Diff(r.Context(), projectID, fromID, toID)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{err.Error(), "not_found"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, map[string]any{"from": fromID, "to": toID, "files": diffs})
+}
+
+func handleRestoreSnapshot(w http.ResponseWriter, r *http.Request, projectID, snapshotID string) {
+	m, err := snapshotStore.Restore(r.Context(), projectID, snapshotID, snapshot.Options{Author: snapshotAuthor(r)})
+	if err != nil {
+		httpx.Respond(w, r, http.StatusInternalServerError, ErrorBody{"failed to restore snapshot", "internal_error"})
+		return
+	}
+	httpx.Respond(w, r, http.StatusOK, m)
+}
+
+// handleCompileDiagnostics parses a job's log on demand and returns the
+// structured diagnostics logparser extracts from it.
+func handleCompileDiagnostics(w http.ResponseWriter, r *http.Request, projectID, jobID string) {
+	logPath := filepath.Join(projectDir(projectID), "compile", "logs", jobID+".txt")
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		httpx.Respond(w, r, http.StatusNotFound, ErrorBody{"log not found", "not_found"})
 		return
 	}
-	writeJSON(w, http.StatusAccepted, CompileAccepted{JobID: jobID, Revision: rev})
+	httpx.Respond(w, r, http.StatusOK, map[string]any{"jobId": jobID, "diagnostics": logparser.Parse(b)})
 }
 
 func handleFiles(w http.ResponseWriter, r *http.Request) {
@@ -663,16 +1798,32 @@ func handleFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	projectID, rel := parts[0], parts[1]
-	full, ok := safeJoin(projectDir(projectID), rel)
+	if !requireRole(w, r, projectID, auth.RoleViewer) {
+		return
+	}
+	key, ok := storageKey(projectID, rel)
 	if !ok {
 		http.NotFound(w, r)
 		return
 	}
+
+	if url, err := fileStorage.PresignedGetURL(r.Context(), key, 15*time.Minute); err == nil {
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+
+	rc, err := fileStorage.Open(r.Context(), key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer rc.Close()
+
 	w.Header().Set("Cache-Control", "no-store")
-	if ct := mime.TypeByExtension(filepath.Ext(full)); ct != "" {
+	if ct := mime.TypeByExtension(filepath.Ext(key)); ct != "" {
 		w.Header().Set("Content-Type", ct)
 	}
-	http.ServeFile(w, r, full)
+	io.Copy(w, rc)
 }
 
 func handleWSProjects(w http.ResponseWriter, r *http.Request) {
@@ -681,6 +1832,11 @@ func handleWSProjects(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing projectId", http.StatusBadRequest)
 		return
 	}
+	if authStore.HasMembers(projectID) && !auth.Allow(r.Context(), authStore, projectID, auth.RoleViewer) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	canEdit := !authStore.HasMembers(projectID) || auth.Allow(r.Context(), authStore, projectID, auth.RoleEditor)
 
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -688,9 +1844,14 @@ func handleWSProjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer c.Close()
+	metrics.WSConnectionsActive.Inc()
+	defer metrics.WSConnectionsActive.Dec()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	defer collabHub.UnsubscribeAll(c)
+
+	clientID := genToken()
 
 	for {
 		_, data, err := c.ReadMessage()
@@ -703,26 +1864,65 @@ func handleWSProjects(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		t, _ := payload["type"].(string)
+		metrics.WSMessagesTotal.WithLabelValues(t, "in").Inc()
+		entry, _ := payload["entryFile"].(string)
+		if entry == "" {
+			entry = "main.tex"
+		}
 
 		switch t {
+		case "subscribe":
+			collabHub.Subscribe(projectID, entry, c)
+			sendSnapshot(c, projectID, entry)
+
+		case "unsubscribe":
+			collabHub.Unsubscribe(projectID, entry, c)
+
+		case "requestSnapshot":
+			sendSnapshot(c, projectID, entry)
+
+		case "presence":
+			var cursor *CursorCursor
+			if cm, ok := payload["cursor"].(map[string]any); ok {
+				line, _ := cm["line"].(float64)
+				col, _ := cm["col"].(float64)
+				cursor = &CursorCursor{Line: int(line), Col: int(col)}
+			}
+			collabHub.Broadcast(projectID, entry, c, WSPresence{
+				Type: "presence", ProjectID: projectID, TS: time.Now().UTC().Format(time.RFC3339),
+				EntryFile: entry, ClientID: clientID, Cursor: cursor,
+			})
+
 		case "docUpdate":
-			content, _ := payload["content"].(string)
-			entry, _ := payload["path"].(string)
-			if entry == "" {
-				entry = "main.tex"
+			if !canEdit {
+				sendAck(c, projectID, "docUpdate", nil, map[string]any{"message": "editor role required"})
+				continue
+			}
+			change := collab.Change{}
+			if bv, ok := payload["baseRevision"].(float64); ok {
+				change.BaseRevision = int(bv)
 			}
-			var revStr string
-			switch rv := payload["revision"].(type) {
-			case float64:
-				revStr = strconv.FormatInt(int64(rv), 10)
-			case string:
-				revStr = rv
-			default:
-				revStr = genToken()
+			if rawOps, ok := payload["ops"].([]any); ok {
+				change.Ops = decodeOps(rawOps)
+			}
+			doc, err := docRegistry.Get(projectID, entry)
+			if err != nil {
+				sendAck(c, projectID, "docUpdate", nil, map[string]any{"message": "document unavailable"})
+				continue
 			}
-			projectState.setLatestRevision(projectID, revStr)
+			transformed, revision, err := doc.Submit(change)
+			if err != nil {
+				sendAck(c, projectID, "docUpdate", nil, map[string]any{"message": err.Error()})
+				continue
+			}
+			projectState.setLatestRevision(projectID, strconv.Itoa(revision))
+			content, _ := doc.Snapshot()
 			projectState.setBuffer(projectID, entry, content)
-			sendAck(c, projectID, "docUpdate", payload["revision"], nil)
+			sendAck(c, projectID, "docUpdate", revision, nil)
+			collabHub.Broadcast(projectID, entry, c, WSOpMessage{
+				Type: "docUpdate", ProjectID: projectID, TS: time.Now().UTC().Format(time.RFC3339),
+				EntryFile: entry, Ops: transformed, Revision: revision,
+			})
 
 		case "requestCompile":
 			entry, _ := payload["path"].(string)
@@ -737,16 +1937,18 @@ func handleWSProjects(w http.ResponseWriter, r *http.Request) {
 			}
 			sendAck(c, projectID, "requestCompile", payload["revision"], nil)
 			now := time.Now().UTC().Format(time.RFC3339)
+			metrics.WSMessagesTotal.WithLabelValues("compileQueued", "out").Inc()
 			c.WriteJSON(CompileQueued{Type: "compileQueued", ProjectID: projectID, TS: now, JobID: jobID, Revision: revStr})
-			go watchJobStatus(ctx, c, projectID, jobID, revStr)
+			go watchJobStatus(ctx, c, projectID, jobID, revStr, "pdflatex")
 
 		case "ping":
+			metrics.WSMessagesTotal.WithLabelValues("pong", "out").Inc()
 			c.WriteJSON(WSPong{Type: "pong", ProjectID: projectID, TS: time.Now().UTC().Format(time.RFC3339)})
 		}
 	}
 }
 
-func watchJobStatus(ctx context.Context, c *websocket.Conn, projectID, jobID, rev string) {
+func watchJobStatus(ctx context.Context, c *websocket.Conn, projectID, jobID, rev, engine string) {
 	statusPath := filepath.Join(projectDir(projectID), "compile", "status", jobID+".json")
 	logPath := filepath.Join(projectDir(projectID), "compile", "logs", jobID+".txt")
 	ticker := time.NewTicker(250 * time.Millisecond)
@@ -765,6 +1967,7 @@ func watchJobStatus(ctx context.Context, c *websocket.Conn, projectID, jobID, re
 				State      string `json:"state"`
 				StartedAt  string `json:"startedAt"`
 				FinishedAt string `json:"finishedAt"`
+				DurationMs int64  `json:"durationMs"`
 			}
 			if json.Unmarshal(b, &s) != nil {
 				continue
@@ -772,40 +1975,184 @@ func watchJobStatus(ctx context.Context, c *websocket.Conn, projectID, jobID, re
 			now := time.Now().UTC().Format(time.RFC3339)
 			switch s.State {
 			case "running":
+				metrics.WSMessagesTotal.WithLabelValues("compileStarted", "out").Inc()
 				c.WriteJSON(CompileStarted{Type: "compileStarted", ProjectID: projectID, TS: now, JobID: jobID, Revision: rev, StartedAt: s.StartedAt})
 			case "success":
+				metrics.CompileQueueDepth.Dec()
+				metrics.CompileJobsTotal.WithLabelValues(engine, "success").Inc()
+				metrics.CompileDuration.WithLabelValues(engine).Observe((time.Duration(s.DurationMs) * time.Millisecond).Seconds())
+				metrics.WSMessagesTotal.WithLabelValues("compileSucceeded", "out").Inc()
 				c.WriteJSON(CompileSucceeded{Type: "compileSucceeded", ProjectID: projectID, TS: now, JobID: jobID, Revision: rev, OutputPath: "/files/" + projectID + "/output.pdf", FinishedAt: s.FinishedAt})
+				sendDiagnostics(c, projectID, jobID, rev, logPath)
 				return
 			case "failed":
+				metrics.CompileQueueDepth.Dec()
+				metrics.CompileJobsTotal.WithLabelValues(engine, "failed").Inc()
+				metrics.CompileDuration.WithLabelValues(engine).Observe((time.Duration(s.DurationMs) * time.Millisecond).Seconds())
 				logTail, _ := os.ReadFile(logPath)
+				metrics.WSMessagesTotal.WithLabelValues("compileFailed", "out").Inc()
 				c.WriteJSON(CompileFailed{Type: "compileFailed", ProjectID: projectID, TS: now, JobID: jobID, Revision: rev, Error: string(logTail), FinishedAt: s.FinishedAt})
+				sendDiagnostics(c, projectID, jobID, rev, logPath)
 				return
 			}
 		}
 	}
 }
 
+// sendDiagnostics parses a job's log, if present, and pushes a
+// compileDiagnostics message; it's a no-op when parsing finds nothing.
+func sendDiagnostics(c *websocket.Conn, projectID, jobID, revision, logPath string) {
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		return
+	}
+	diags := logparser.Parse(b)
+	if len(diags) == 0 {
+		return
+	}
+	metrics.WSMessagesTotal.WithLabelValues("compileDiagnostics", "out").Inc()
+	c.WriteJSON(CompileDiagnosticsMsg{
+		Type: "compileDiagnostics", ProjectID: projectID, TS: time.Now().UTC().Format(time.RFC3339),
+		JobID: jobID, Revision: revision, Diagnostics: diags,
+	})
+}
+
 func enqueueJob(projectID, entryFile, engine, revision, requestor string) (string, error) {
 	jobID := uuid()
-	root := projectDir(projectID)
-	job := map[string]any{
-		"jobId":     jobID,
-		"projectId": projectID,
-		"entryFile": entryFile,
-		"engine":    engine,
-		"revision":  revision,
+	job := compile.Job{
+		JobID:     jobID,
+		ProjectID: projectID,
+		EntryFile: entryFile,
+		Engine:    engine,
+		Revision:  revision,
 	}
-	b, _ := json.MarshalIndent(job, "", "  ")
-	qpath := filepath.Join(root, "compile", "queue", jobID+".json")
-	if err := os.WriteFile(qpath, b, 0o644); err != nil {
+	if err := compileQueue.Push(context.Background(), job); err != nil {
 		return "", err
 	}
+	// Only fully accurate for jobs a watchJobStatus goroutine later observes
+	// to a terminal state; REST-initiated jobs that the caller never polls
+	// are never decremented. That asymmetry predates this metric.
+	metrics.CompileQueueDepth.Inc()
+	root := projectDir(projectID)
+	statusPath := filepath.Join(root, "compile", "status", jobID+".json")
+	os.WriteFile(statusPath, []byte(`{"state":"queued"}`), 0o644)
+
 	if isSimulationEnabled() {
 		go simulateCompilation(projectID, jobID, entryFile, engine, revision)
 	}
 	return jobID, nil
 }
 
+// fetchProjectFiles pulls a project's source tree out of fileStorage into
+// a local directory so the compile worker can hand it to pdflatex, even
+// when the API and the worker don't share a filesystem.
+func fetchProjectFiles(ctx context.Context, projectID, destDir string) error {
+	prefix := projectID + "/"
+	objects, err := fileStorage.ReadDir(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if obj.IsDir {
+			continue
+		}
+		rel := strings.TrimPrefix(obj.Name, prefix)
+		if rel == "" || strings.HasPrefix(rel, "compile/") || rel == "output.pdf" {
+			continue
+		}
+		if err := copyObjectToFile(ctx, obj.Name, filepath.Join(destDir, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishCompiledFile uploads a worker-produced artifact (e.g. output.pdf)
+// back into fileStorage under the project's key prefix.
+func publishCompiledFile(ctx context.Context, projectID, key, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	pKey, _ := storageKey(projectID, key)
+	return fileStorage.WriteFile(ctx, pKey, f, info.Size())
+}
+
+func copyObjectToFile(ctx context.Context, key, dst string) error {
+	rc, err := fileStorage.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, rc)
+	return err
+}
+
+// hasRealEngine reports whether a real LaTeX engine is installed, so the
+// service can fall back to the deterministic simulateCompilation stub in
+// dev/test environments where none is on PATH.
+func hasRealEngine() bool {
+	for _, bin := range []string{"pdflatex", "xelatex", "lualatex", "latexmk", "tectonic"} {
+		if _, err := exec.LookPath(bin); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// tikzToolsAvailable reports whether the external tools svg.CompileTikz
+// shells out to are installed, so diagram preview can skip TikZ rendering
+// (keeping ASCII diagrams) rather than failing outright in environments
+// without a LaTeX toolchain.
+func tikzToolsAvailable() bool {
+	_, errTex := exec.LookPath("pdflatex")
+	_, errSVG := exec.LookPath("pdf2svg")
+	return errTex == nil && errSVG == nil
+}
+
+func sendSnapshot(c *websocket.Conn, projectID, entry string) {
+	doc, err := docRegistry.Get(projectID, entry)
+	if err != nil {
+		return
+	}
+	content, revision := doc.Snapshot()
+	metrics.WSMessagesTotal.WithLabelValues("snapshot", "out").Inc()
+	c.WriteJSON(WSSnapshot{
+		Type: "snapshot", ProjectID: projectID, TS: time.Now().UTC().Format(time.RFC3339),
+		EntryFile: entry, Content: content, Revision: revision,
+	})
+}
+
+// decodeOps converts the generic []any produced by decoding a docUpdate's
+// "ops" field into []collab.Op.
+func decodeOps(raw []any) []collab.Op {
+	ops := make([]collab.Op, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		kind, _ := m["kind"].(string)
+		n, _ := m["n"].(float64)
+		text, _ := m["text"].(string)
+		ops = append(ops, collab.Op{Kind: collab.OpKind(kind), N: int(n), Text: text})
+	}
+	return ops
+}
+
 func sendAck(c *websocket.Conn, projectID, op string, revision any, errObj map[string]any) {
 	ack := map[string]any{
 		"type":      "ack",
@@ -817,36 +2164,63 @@ func sendAck(c *websocket.Conn, projectID, op string, revision any, errObj map[s
 	if errObj != nil {
 		ack["error"] = errObj
 	}
+	metrics.WSMessagesTotal.WithLabelValues("ack", "out").Inc()
 	c.WriteJSON(ack)
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
-	})
-}
-
-func projectDir(projectID string) string {
-	return filepath.Join(latexRoot, projectID)
+// recordHTTPMetrics is httplog.Middleware's observe hook: it feeds every
+// completed request into the http_requests_total counter and
+// http_request_duration_seconds histogram.
+func recordHTTPMetrics(path, method string, status int, dur time.Duration) {
+	route := routeLabel(path)
+	statusStr := strconv.Itoa(status)
+	metrics.HTTPRequestsTotal.WithLabelValues(route, method, statusStr).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(route, method, statusStr).Observe(dur.Seconds())
+}
+
+// routeLabel collapses a request path into a low-cardinality route label
+// by replacing project/job/snapshot IDs (our uuid()/newSnapshotID() hex
+// strings) with ":id", so per-project traffic doesn't create a new
+// metrics series per project.
+func routeLabel(path string) string {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segs {
+		if i >= 2 && looksLikeID(seg) {
+			segs[i] = ":id"
+		}
+	}
+	return "/" + strings.Join(segs, "/")
 }
 
-func safeJoin(root, requested string) (string, bool) {
-	cleanRel := path.Clean(filepath.ToSlash(requested))
-	if strings.HasPrefix(cleanRel, "../") || strings.Contains(cleanRel, "/../") {
-		return "", false
+func looksLikeID(s string) bool {
+	if s == "" {
+		return false
 	}
-	full := filepath.Join(root, cleanRel)
-	if !strings.HasPrefix(full, filepath.Clean(root)) {
-		return "", false
+	for _, r := range s {
+		if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || r == '-') {
+			return false
+		}
 	}
-	return full, true
+	return true
 }
 
+func projectDir(projectID string) string {
+	return filepath.Join(latexRoot, projectID)
+}
+
+// isSimulationEnabled reports whether compiles should fall back to the
+// deterministic simulateCompilation stub: the operator forced it via
+// SIMULATE_COMPILER, LATEX_ENGINE is left at its "stub" default, or no
+// configured engine binary is actually on PATH.
 func isSimulationEnabled() bool {
 	v := os.Getenv("SIMULATE_COMPILER")
-	return v == "1" || strings.ToLower(v) == "true"
+	if v == "1" || strings.ToLower(v) == "true" {
+		return true
+	}
+	if envOr("LATEX_ENGINE", "stub") == "stub" {
+		return true
+	}
+	return !hasRealEngine()
 }
 
 func simulateCompilation(projectID, jobID, entryFile, engine, revision string) {
@@ -863,10 +2237,16 @@ func simulateCompilation(projectID, jobID, entryFile, engine, revision string) {
 	writePlaceholderPDF(filepath.Join(root, "output.pdf"), currentContent)
 
 	os.WriteFile(statusPath, []byte(`{"state":"success"}`), 0o644)
+	snapshotOnCompileSuccess(context.Background(), projectID)
 }
 
 func writePlaceholderPDF(dst, content string) error {
-	// A minimal PDF structure
+	// A minimal PDF structure. /CreationDate, /ModDate, and /ID vary on
+	// every call like a real engine's would; callers that need
+	// byte-for-byte reproducible output (e.g. the golden test harness)
+	// should normalize them away rather than expect them to be stable.
+	now := time.Now().UTC().Format("20060102150405")
+	id := fmt.Sprintf("%x", sha256.Sum256([]byte(content+now)))
 	pdfContent := fmt.Sprintf(
 		"%%PDF-1.4\n1 0 obj<</Type/Catalog/Pages 2 0 R>>endobj\n"+
 			"2 0 obj<</Type/Pages/Count 1/Kids[3 0 R]>>endobj\n"+
@@ -876,8 +2256,8 @@ func writePlaceholderPDF(dst, content string) error {
 			"xref\n0 6\n0000000000 65535 f \n"+
 			"0000000010 00000 n \n0000000065 00000 n \n0000000122 00000 n \n"+
 			"0000000280 00000 n \n0000000425 00000 n \n"+
-			"trailer<</Size 6/Root 1 0 R>>\nstartxref\n%d\n%%%%EOF",
-		len(content)+25, content, 515+len(content)-len("Hello, LaTeX Preview!"),
+			"trailer<</Size 6/Root 1 0 R/CreationDate(D:%s)/ModDate(D:%s)/ID[<%s><%s>]>>\nstartxref\n%d\n%%%%EOF",
+		len(content)+25, content, now, now, id, id, 515+len(content)-len("Hello, LaTeX Preview!"),
 	)
 	return os.WriteFile(dst, []byte(pdfContent), 0o644)
 }